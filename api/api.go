@@ -0,0 +1,220 @@
+// Package api serves registry objects over HTTP as JSON or plain text.
+//
+// The handler answers
+//
+//	GET /api/{schema}/{name}   an object
+//	GET /api/search?q={name}   objects of any schema named q
+//	GET /api/schema/{name}     a schema by object name or schema name
+//
+// Objects are written as JSON unless the Accept header prefers text/plain.
+// Responses carry an ETag and conditional requests with If-None-Match are
+// answered with 304 Not Modified.
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+// Prefix of the paths served by the handler.
+const Prefix = "/api/"
+
+// Registry is the source of objects served by the handler. *rpsl.RPSL
+// satisfies it.
+type Registry interface {
+	rpsl.Fetcher
+	rpsl.Indexer
+
+	// Schemas returns the current schemas of the registry. It is called on
+	// every request so should be cheap.
+	Schemas() (*rpsl.Schemas, error)
+}
+
+// Handler serves registry objects over HTTP.
+type Handler struct {
+	reg Registry
+}
+
+// New returns a handler serving objects from reg.
+func New(reg Registry) *Handler {
+	return &Handler{reg: reg}
+}
+
+type errorBody struct {
+	Error string `json:"error"`
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	if !strings.HasPrefix(r.URL.Path, Prefix) {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	path := strings.TrimPrefix(r.URL.Path, Prefix)
+
+	var (
+		lis rpsl.ListObject
+		err error
+	)
+	switch {
+	case path == "search":
+		q := r.URL.Query().Get("q")
+		if q == "" {
+			writeError(w, http.StatusBadRequest, "missing query parameter q")
+			return
+		}
+		lis, err = h.reg.FindObject(q)
+		if err == rpsl.NotFound {
+			lis, err = rpsl.ListObject{}, nil
+		}
+
+	case strings.HasPrefix(path, "schema/"):
+		var dom *rpsl.Object
+		dom, err = h.schema(strings.TrimPrefix(path, "schema/"))
+		lis = rpsl.ListObject{dom}
+
+	default:
+		sp := strings.SplitN(path, "/", 2)
+		if len(sp) != 2 || sp[0] == "" || sp[1] == "" {
+			writeError(w, http.StatusNotFound, "not found")
+			return
+		}
+		var dom *rpsl.Object
+		if err = h.known(sp[0]); err == nil {
+			dom, err = h.reg.LoadObject(sp[0], sp[1])
+		}
+		lis = rpsl.ListObject{dom}
+	}
+
+	if err == rpsl.NotFound {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.write(w, r, path == "search", lis)
+}
+
+// schema loads a schema object by name such as INETNUM-SCHEMA or inetnum.
+func (h *Handler) schema(name string) (*rpsl.Object, error) {
+	dom, err := h.reg.LoadObject("schema", name)
+	if err == rpsl.NotFound && !strings.HasSuffix(strings.ToUpper(name), "-SCHEMA") {
+		return h.reg.LoadObject("schema", strings.ToUpper(name)+"-SCHEMA")
+	}
+	return dom, err
+}
+
+// known returns NotFound unless the registry has a schema for objects of
+// schema.
+func (h *Handler) known(schema string) error {
+	schemas, err := h.reg.Schemas()
+	if err != nil {
+		return err
+	}
+	if schemas.Get(schema) == nil {
+		return rpsl.NotFound
+	}
+	return nil
+}
+
+func (h *Handler) write(w http.ResponseWriter, r *http.Request, list bool, lis rpsl.ListObject) {
+	var (
+		body []byte
+		err  error
+	)
+
+	contentType := "application/json"
+	switch {
+	case acceptsText(r.Header.Get("Accept")):
+		contentType = "text/plain; charset=utf-8"
+		body = []byte(lis.String() + "\n")
+	case list:
+		body, err = json.Marshal(lis)
+	default:
+		body, err = json.Marshal(lis[0])
+	}
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	sum := sha256.Sum256(body)
+	etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Vary", "Accept")
+	if matchETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusOK)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+func writeError(w http.ResponseWriter, code int, msg string) {
+	body, _ := json.Marshal(errorBody{Error: msg})
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
+
+// acceptsText reports whether the Accept header prefers text/plain over JSON.
+func acceptsText(accept string) bool {
+	var textQ, jsonQ float64 = -1, -1
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		mediaType := strings.ToLower(strings.TrimSpace(params[0]))
+
+		q := 1.0
+		for _, p := range params[1:] {
+			p = strings.TrimSpace(p)
+			if strings.HasPrefix(p, "q=") {
+				if v, err := strconv.ParseFloat(p[2:], 64); err == nil {
+					q = v
+				}
+			}
+		}
+
+		switch mediaType {
+		case "text/plain", "text/*":
+			if q > textQ {
+				textQ = q
+			}
+		case "application/json", "application/*", "*/*":
+			if q > jsonQ {
+				jsonQ = q
+			}
+		}
+	}
+
+	return textQ > 0 && textQ > jsonQ
+}
+
+// matchETag reports whether an If-None-Match header matches etag.
+func matchETag(header, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || strings.TrimPrefix(tag, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
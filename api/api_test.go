@@ -0,0 +1,122 @@
+package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matryer/is"
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/api"
+)
+
+func TestHandler(t *testing.T) {
+	is := is.New(t)
+
+	h := api.New(rpsl.NewRPSL(rpsl.WithFS(os.DirFS("../testdata/registry"))))
+
+	get := func(path string, header ...string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		for i := 0; i+1 < len(header); i += 2 {
+			req.Header.Set(header[i], header[i+1])
+		}
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, req)
+		return w
+	}
+
+	w := get("/api/inetnum/172.21.64.0/29")
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Header().Get("Content-Type"), "application/json")
+	var attrs [][]interface{}
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &attrs))
+	is.Equal(attrs[1][0], "cidr")
+
+	etag := w.Header().Get("ETag")
+	is.True(etag != "")
+	w = get("/api/inetnum/172.21.64.0_29", "If-None-Match", `"other", `+etag)
+	is.Equal(w.Code, http.StatusNotModified)
+
+	w = get("/api/person/XUU-DN42", "Accept", "application/json;q=0.5, text/plain")
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Header().Get("Content-Type"), "text/plain; charset=utf-8")
+	is.True(strings.HasPrefix(w.Body.String(), "person:             Xuu\n"))
+	is.True(w.Header().Get("ETag") != etag)
+
+	w = get("/api/search?q=XUU-MNT")
+	is.Equal(w.Code, http.StatusOK)
+	var lis [][][]interface{}
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &lis))
+	is.Equal(len(lis), 1)
+
+	w = get("/api/search?q=NOTHING")
+	is.Equal(w.Code, http.StatusOK)
+	is.Equal(w.Body.String(), "[]")
+
+	w = get("/api/schema/person", "Accept", "text/plain")
+	is.Equal(w.Code, http.StatusOK)
+	is.True(strings.HasPrefix(w.Body.String(), "schema:             PERSON-SCHEMA\n"))
+
+	w = get("/api/person/NOBODY-DN42")
+	is.Equal(w.Code, http.StatusNotFound)
+	is.Equal(w.Body.String(), `{"error":"not found"}`)
+
+	// Only objects of known schemas are served.
+	for _, path := range []string{"/api/unknown/FOO", "/api/..%2Fschema/PERSON-SCHEMA", "/api/person%0A/XUU-DN42"} {
+		w = get(path)
+		is.Equal(w.Code, http.StatusNotFound)
+	}
+
+	w = get("/api/search")
+	is.Equal(w.Code, http.StatusBadRequest)
+
+	req := httptest.NewRequest("POST", "/api/person/XUU-DN42", nil)
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, req)
+	is.Equal(w.Code, http.StatusMethodNotAllowed)
+}
+
+func TestHandlerReload(t *testing.T) {
+	is := is.New(t)
+
+	reg := fstest.MapFS{
+		"schema/PERSON-SCHEMA": {Data: []byte("schema: PERSON-SCHEMA\nkey: person required single\nkey: nic-hdl required single primary\n")},
+		"person/XUU-DN42":      {Data: []byte("person: Xuu\nnic-hdl: XUU-DN42\n")},
+	}
+	r := rpsl.NewRPSL(rpsl.WithFS(reg))
+	h := api.New(r)
+
+	get := func(path string) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		return w.Code
+	}
+
+	is.Equal(get("/api/person/XUU-DN42"), http.StatusOK)
+	is.Equal(get("/api/role/SOURIS-DN42"), http.StatusNotFound)
+
+	// Schemas added by a reload are known to the handler.
+	reg["schema/ROLE-SCHEMA"] = &fstest.MapFile{Data: []byte("schema: ROLE-SCHEMA\nkey: role required single\nkey: nic-hdl required single primary\n")}
+	reg["role/SOURIS-DN42"] = &fstest.MapFile{Data: []byte("role: Souris\nnic-hdl: SOURIS-DN42\n")}
+	is.Equal(get("/api/role/SOURIS-DN42"), http.StatusNotFound)
+
+	r.Reload(rpsl.WithFS(reg))
+	is.Equal(get("/api/role/SOURIS-DN42"), http.StatusOK)
+
+	// Registries in memory follow their current snapshot.
+	m, err := rpsl.LoadFS(context.Background(), reg, 0)
+	is.NoErr(err)
+	r.Reload(rpsl.WithFetcher(m))
+	is.Equal(get("/api/role/SOURIS-DN42"), http.StatusOK)
+
+	delete(reg, "schema/ROLE-SCHEMA")
+	next, err := rpsl.LoadFS(context.Background(), reg, 0)
+	is.NoErr(err)
+	m.Swap(next.Snapshot())
+	is.Equal(get("/api/role/SOURIS-DN42"), http.StatusNotFound)
+}
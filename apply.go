@@ -0,0 +1,327 @@
+package rpsl
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Store is a Fetcher that can persist changes.
+type Store interface {
+	Fetcher
+	SaveObject(dom *Object) error
+	DeleteObject(schema, name string) error
+}
+
+// ReadOnly is returned when saving to a registry that can't be written.
+var ReadOnly = errors.New("registry is read only")
+
+// SaveObject writes the object to the registry.
+func (r *RPSL) SaveObject(dom *Object) error {
+	fetch, _, _ := r.backends()
+	if s, ok := fetch.(Store); ok {
+		return s.SaveObject(dom)
+	}
+	return ReadOnly
+}
+
+// DeleteObject removes the object from the registry.
+func (r *RPSL) DeleteObject(schema, name string) error {
+	fetch, _, _ := r.backends()
+	if s, ok := fetch.(Store); ok {
+		return s.DeleteObject(schema, name)
+	}
+	return ReadOnly
+}
+
+// ChangeType is the kind of change made to an object.
+type ChangeType int
+
+const (
+	ChangeCreate ChangeType = iota
+	ChangeModify
+	ChangeDelete
+)
+
+func (t ChangeType) String() string {
+	switch t {
+	case ChangeCreate:
+		return "create"
+	case ChangeModify:
+		return "modify"
+	case ChangeDelete:
+		return "delete"
+	default:
+		return fmt.Sprintf("ChangeType(%d)", int(t))
+	}
+}
+
+// Change to an object in the registry. For deletes only the schema and
+// primary key of Object are used.
+type Change struct {
+	Type   ChangeType
+	Object *Object
+}
+
+// Changes derives the changes of a transaction by comparing its objects with
+// those in f. Objects with a delete attribute are deleted.
+func (tx *Transaction) Changes(f Fetcher, schemas *Schemas) ([]*Change, error) {
+	lis := make([]*Change, len(tx.Objects))
+	for i, dom := range tx.Objects {
+		schemas.Apply(dom)
+
+		if dom.Get("delete") != nil {
+			lis[i] = &Change{Type: ChangeDelete, Object: dom}
+			continue
+		}
+
+		_, err := f.LoadObject(dom.Schema(), dom.Name())
+		switch {
+		case err == NotFound:
+			lis[i] = &Change{Type: ChangeCreate, Object: dom}
+		case err != nil:
+			return nil, err
+		default:
+			lis[i] = &Change{Type: ChangeModify, Object: dom}
+		}
+	}
+
+	return lis, nil
+}
+
+type errorList []error
+
+func (lis errorList) Error() string {
+	s := make([]string, len(lis))
+	for i, err := range lis {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+type objectKey [2]string
+
+// overlay presents the state of a store after applying changes.
+type overlay struct {
+	Fetcher
+	objects map[objectKey]*Object
+}
+
+func (o *overlay) LoadObject(schema, name string) (*Object, error) {
+	if dom, ok := o.objects[objectKey{schema, name}]; ok {
+		if dom == nil {
+			return nil, NotFound
+		}
+		return dom, nil
+	}
+
+	return o.Fetcher.LoadObject(schema, name)
+}
+
+// resolves reports whether a lookup matches an object in f.
+func resolves(f Fetcher, schemas *Schemas, arg *LookupArg) (bool, error) {
+	for _, choice := range arg.Choices {
+		for _, schema := range schemas.Lookups(choice) {
+			_, err := f.LoadObject(schema, arg.Value)
+			if err == nil {
+				return true, nil
+			}
+			if err != NotFound {
+				return false, err
+			}
+		}
+	}
+
+	return false, nil
+}
+
+// ApplyChanges to store all-or-nothing. Every object is validated with its
+// schema and the references of changed objects are checked against the state
+// after the changes. When the store is also a Lister, deleted objects must not
+// be referenced by any remaining object. If writing a change fails, the
+// changes already written are rolled back.
+func ApplyChanges(store Store, schemas *Schemas, changes []*Change) error {
+	var errs errorList
+
+	post := &overlay{Fetcher: store, objects: make(map[objectKey]*Object)}
+	old := make([]*Object, len(changes))
+
+	for i, c := range changes {
+		dom := c.Object
+		schemas.Apply(dom)
+
+		schema := schemas.Get(dom.Schema())
+		if schema == nil {
+			errs = append(errs, fmt.Errorf("%s %s: unknown schema", dom.Schema(), dom.Name()))
+			continue
+		}
+
+		key := objectKey{dom.Schema(), dom.Name()}
+		if _, ok := post.objects[key]; ok {
+			errs = append(errs, fmt.Errorf("%s %s: changed more than once", key[0], key[1]))
+			continue
+		}
+
+		prev, err := store.LoadObject(key[0], key[1])
+		switch {
+		case err == NotFound:
+			prev = nil
+		case err != nil:
+			return err
+		}
+		old[i] = prev
+
+		switch {
+		case c.Type == ChangeCreate && prev != nil:
+			errs = append(errs, fmt.Errorf("%s %s: already exists", key[0], key[1]))
+		case c.Type != ChangeCreate && prev == nil:
+			errs = append(errs, fmt.Errorf("%s %s: does not exist", key[0], key[1]))
+		}
+
+		if c.Type == ChangeDelete {
+			post.objects[key] = nil
+			continue
+		}
+
+		if err := schema.Validate(dom); err != nil {
+			errs = append(errs, err)
+		}
+		post.objects[key] = dom
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	for _, c := range changes {
+		if c.Type == ChangeDelete {
+			continue
+		}
+		if err := checkReferences(post, schemas, c.Object); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if list, ok := store.(Lister); ok {
+		if err := checkDeleted(post, list, schemas, changes); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	for i, c := range changes {
+		var err error
+		if c.Type == ChangeDelete {
+			err = store.DeleteObject(c.Object.Schema(), c.Object.Name())
+		} else {
+			err = store.SaveObject(c.Object)
+		}
+
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s %s: %w", c.Type, c.Object.Schema(), c.Object.Name(), err))
+			return append(errs, rollback(store, changes[:i], old[:i])...)
+		}
+	}
+
+	return nil
+}
+
+func checkReferences(post Fetcher, schemas *Schemas, dom *Object) error {
+	var missing []string
+	for _, arg := range dom.References() {
+		ok, err := resolves(post, schemas, arg)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			missing = append(missing, arg.String())
+		}
+	}
+
+	if len(missing) > 0 {
+		return fmt.Errorf("%s %s: unresolved references %s", dom.Schema(), dom.Name(), strings.Join(missing, ", "))
+	}
+
+	return nil
+}
+
+// checkDeleted ensures no object left after the changes refers to a deleted object.
+func checkDeleted(post *overlay, list Lister, schemas *Schemas, changes []*Change) error {
+	deleted := make(map[objectKey]bool)
+	for _, c := range changes {
+		if c.Type == ChangeDelete {
+			deleted[objectKey{c.Object.Schema(), c.Object.Name()}] = true
+		}
+	}
+	if len(deleted) == 0 {
+		return nil
+	}
+
+	var errs errorList
+	for _, schema := range schemas.Items() {
+		lis, err := list.ListObjects(schema.Name)
+		if err != nil {
+			return err
+		}
+
+		for _, dom := range lis {
+			if _, ok := post.objects[objectKey{dom.Schema(), dom.Name()}]; ok {
+				continue
+			}
+			schemas.Apply(dom)
+
+			for _, arg := range dom.References() {
+				if !refersTo(deleted, schemas, arg) {
+					continue
+				}
+				ok, err := resolves(post, schemas, arg)
+				if err != nil {
+					return err
+				}
+				if !ok {
+					errs = append(errs, fmt.Errorf("%s %s: references deleted %s", dom.Schema(), dom.Name(), arg))
+				}
+			}
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+
+	return nil
+}
+
+func refersTo(keys map[objectKey]bool, schemas *Schemas, arg *LookupArg) bool {
+	for _, choice := range arg.Choices {
+		for _, schema := range schemas.Lookups(choice) {
+			if keys[objectKey{schema, arg.Value}] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// rollback restores the objects replaced by changes in reverse order.
+func rollback(store Store, changes []*Change, old []*Object) []error {
+	var errs []error
+	for i := len(changes) - 1; i >= 0; i-- {
+		c := changes[i]
+
+		var err error
+		if old[i] == nil {
+			err = store.DeleteObject(c.Object.Schema(), c.Object.Name())
+		} else {
+			err = store.SaveObject(old[i])
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("rollback %s %s: %w", c.Object.Schema(), c.Object.Name(), err))
+		}
+	}
+
+	return errs
+}
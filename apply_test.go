@@ -0,0 +1,114 @@
+package rpsl_test
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+type failStore struct {
+	rpsl.Store
+	fail string
+}
+
+func (s *failStore) SaveObject(dom *rpsl.Object) error {
+	if dom.Name() == s.fail {
+		return errors.New("disk full")
+	}
+	return s.Store.SaveObject(dom)
+}
+
+func TestApplyChanges(t *testing.T) {
+	is := is.New(t)
+
+	lis := rpsl.ParseAll(strings.NewReader(cleanDoc(txtAllObjects)))
+	schemas, err := rpsl.ParseSchemas(lis)
+	is.NoErr(err)
+
+	r := rpsl.NewRPSL(rpsl.WithRPSLDir(writeRegistry(t, lis)))
+
+	person := func(nic, mnt string) *rpsl.Object {
+		return rpsl.ParseObject(cleanDoc(`
+                person:  Foo
+                nic-hdl: ` + nic + `
+                mnt-by:  ` + mnt + `
+                source:  DN42
+                `))
+	}
+
+	// Validation and reference errors write nothing.
+	invalid := rpsl.ParseObject("person: Bar\nnic-hdl: BAR-DN42\nmnt-by: XUU-MNT\nfoo: bar")
+	err = rpsl.ApplyChanges(r, schemas, []*rpsl.Change{
+		{Type: rpsl.ChangeCreate, Object: person("FOO-DN42", "MISSING-MNT")},
+		{Type: rpsl.ChangeCreate, Object: invalid},
+	})
+	is.True(err != nil)
+	is.Equal(err.Error(), "person BAR-DN42: missing required key source; unknown key foo")
+
+	err = rpsl.ApplyChanges(r, schemas, []*rpsl.Change{
+		{Type: rpsl.ChangeCreate, Object: person("FOO-DN42", "MISSING-MNT")},
+	})
+	is.True(err != nil)
+	is.Equal(err.Error(), "person FOO-DN42: unresolved references mntner/MISSING-MNT")
+	_, err = r.LoadObject("person", "FOO-DN42")
+	is.Equal(err, rpsl.NotFound)
+
+	// References resolve against objects created in the same changes.
+	mnt := rpsl.ParseObject("mntner: FOO-MNT\nmnt-by: FOO-MNT\nsource: DN42")
+	err = rpsl.ApplyChanges(r, schemas, []*rpsl.Change{
+		{Type: rpsl.ChangeCreate, Object: person("FOO-DN42", "FOO-MNT")},
+		{Type: rpsl.ChangeCreate, Object: mnt},
+	})
+	is.NoErr(err)
+
+	dom, err := r.LoadObject("person", "FOO-DN42")
+	is.NoErr(err)
+	is.Equal(dom.Get("mnt-by").Text(), "FOO-MNT")
+
+	err = rpsl.ApplyChanges(r, schemas, []*rpsl.Change{
+		{Type: rpsl.ChangeCreate, Object: person("FOO-DN42", "FOO-MNT")},
+		{Type: rpsl.ChangeModify, Object: person("NEW-DN42", "FOO-MNT")},
+	})
+	is.Equal(err.Error(), "person FOO-DN42: already exists\nperson NEW-DN42: does not exist")
+
+	// Deleting a referenced object is refused.
+	err = rpsl.ApplyChanges(r, schemas, []*rpsl.Change{
+		{Type: rpsl.ChangeDelete, Object: mnt},
+	})
+	is.Equal(err.Error(), "person FOO-DN42: references deleted mntner/FOO-MNT")
+
+	err = rpsl.ApplyChanges(r, schemas, []*rpsl.Change{
+		{Type: rpsl.ChangeDelete, Object: mnt},
+		{Type: rpsl.ChangeDelete, Object: person("FOO-DN42", "FOO-MNT")},
+	})
+	is.NoErr(err)
+	_, err = r.LoadObject("mntner", "FOO-MNT")
+	is.Equal(err, rpsl.NotFound)
+
+	// A failed write rolls back earlier changes.
+	store := &failStore{Store: r, fail: "FAIL-DN42"}
+	err = rpsl.ApplyChanges(store, schemas, []*rpsl.Change{
+		{Type: rpsl.ChangeCreate, Object: person("FOO-DN42", "XUU-MNT")},
+		{Type: rpsl.ChangeCreate, Object: person("FAIL-DN42", "XUU-MNT")},
+	})
+	is.Equal(err.Error(), "create person FAIL-DN42: disk full")
+	_, err = r.LoadObject("person", "FOO-DN42")
+	is.Equal(err, rpsl.NotFound)
+
+	tx := rpsl.NewTransaction("DN42", "1", rpsl.ListObject{
+		person("FOO-DN42", "XUU-MNT"),
+		rpsl.ParseObject("mntner: XUU-MNT\ndelete: no longer used"),
+	})
+	changes, err := tx.Changes(r, schemas)
+	is.NoErr(err)
+	is.Equal(changes[0].Type, rpsl.ChangeCreate)
+	is.Equal(changes[1].Type, rpsl.ChangeDelete)
+	is.Equal(changes[1].Type.String(), "delete")
+
+	empty := rpsl.NewRPSL()
+	is.Equal(empty.SaveObject(mnt), rpsl.ReadOnly)
+	is.Equal(empty.DeleteObject("mntner", "FOO-MNT"), rpsl.ReadOnly)
+}
@@ -0,0 +1,253 @@
+package rpsl
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// Decision of an authorisation check with the reasons that led to it.
+type Decision struct {
+	Allow   bool
+	Reasons []string
+}
+
+func (d *Decision) String() string {
+	s := "deny"
+	if d.Allow {
+		s = "allow"
+	}
+	return s + ": " + strings.Join(d.Reasons, "; ")
+}
+
+func (d *Decision) reason(format string, args ...interface{}) {
+	d.Reasons = append(d.Reasons, fmt.Sprintf(format, args...))
+}
+
+// Authoriser decides whether a change is authorised by the maintainers that
+// signed it.
+type Authoriser struct {
+	fetch   Fetcher
+	schemas *Schemas
+}
+
+// NewAuthoriser loads existing objects and parents from f.
+func NewAuthoriser(f Fetcher, schemas *Schemas) *Authoriser {
+	return &Authoriser{fetch: f, schemas: schemas}
+}
+
+// Authorise a change from old to new signed by signers. Old is nil for
+// creations and new is nil for deletions.
+//
+// Modifications and deletions require a signer in the mnt-by of the old
+// object. Creations of objects whose schema has a network-owner require a
+// signer in the mnt-routes (for routes) or mnt-lower of the closest parent,
+// falling back to the parent mnt-by when those are not set. Creations of
+// aut-num objects are checked the same way against the covering as-block.
+// Networks and aut-nums without a parent are denied. Other creations require
+// a signer in the mnt-by of the new object.
+func (a *Authoriser) Authorise(old, new *Object, signers ...string) (*Decision, error) {
+	d := &Decision{}
+
+	switch {
+	case old == nil && new == nil:
+		d.reason("no object")
+		return d, nil
+
+	case old != nil:
+		a.schemas.Apply(old)
+		op := "modify"
+		if new == nil {
+			op = "delete"
+		}
+		err := a.check(d, fmt.Sprintf("%s %s %s", op, old.Schema(), old.Name()), old, []string{"mnt-by"}, signers)
+		return d, err
+	}
+
+	a.schemas.Apply(new)
+	what := fmt.Sprintf("create %s %s", new.Schema(), new.Name())
+
+	parent, err := a.Parent(new)
+	if err != nil {
+		return nil, err
+	}
+	if parent == nil {
+		if a.hasParent(new) {
+			d.reason("%s: no parent object", what)
+			return d, nil
+		}
+		err := a.check(d, what, new, []string{"mnt-by"}, signers)
+		return d, err
+	}
+
+	d.reason("parent is %s %s", parent.Schema(), parent.Name())
+	keys := []string{"mnt-lower", "mnt-by"}
+	if new.Schema() == "route" || new.Schema() == "route6" {
+		keys = []string{"mnt-routes", "mnt-lower", "mnt-by"}
+	}
+	err = a.check(d, what, parent, keys, signers)
+
+	return d, err
+}
+
+// check allows the decision when a signer is listed in the first of keys
+// set on dom.
+func (a *Authoriser) check(d *Decision, what string, dom *Object, keys, signers []string) error {
+	for _, key := range keys {
+		mntners, err := a.mntners(d, dom, key)
+		if err != nil {
+			return err
+		}
+		if len(mntners) == 0 {
+			continue
+		}
+
+		for _, signer := range signers {
+			for _, mnt := range mntners {
+				if signer == mnt {
+					d.Allow = true
+					d.reason("%s: signed by %s listed in %s of %s %s", what, signer, key, dom.Schema(), dom.Name())
+					return nil
+				}
+			}
+		}
+
+		d.reason("%s: requires a signature from %s of %s %s: %s", what, key, dom.Schema(), dom.Name(), strings.Join(mntners, ", "))
+		return nil
+	}
+
+	d.reason("%s: %s %s has no %s", what, dom.Schema(), dom.Name(), strings.Join(keys, " or "))
+	return nil
+}
+
+// mntners returns the maintainers in key that resolve to a mntner object.
+func (a *Authoriser) mntners(d *Decision, dom *Object, key string) ([]string, error) {
+	var lis []string
+	for _, attr := range dom.GetAll(key) {
+		fields := attr.Fields()
+		if len(fields) == 0 {
+			continue
+		}
+
+		ok, err := resolves(a.fetch, a.schemas, &LookupArg{Choices: []string{"mntner"}, Value: fields[0]})
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			d.reason("%s %s does not resolve", key, fields[0])
+			continue
+		}
+		lis = append(lis, fields[0])
+	}
+
+	return lis, nil
+}
+
+// hasParent reports whether objects of the schema of dom must be created
+// within a parent object.
+func (a *Authoriser) hasParent(dom *Object) bool {
+	if dom.Schema() == "aut-num" {
+		return true
+	}
+	schema := a.schemas.Get(dom.Schema())
+	return schema != nil && schema.Object != nil && len(schema.GetAll("network-owner").Fields()) > 0
+}
+
+// Parent returns the closest object of a network-owner schema that contains
+// the network of dom, or the as-block that contains an aut-num. Nil is
+// returned when the schema of dom has no network-owner or no parent exists.
+func (a *Authoriser) Parent(dom *Object) (*Object, error) {
+	if dom.Schema() == "aut-num" {
+		return a.asBlock(dom)
+	}
+
+	schema := a.schemas.Get(dom.Schema())
+	if schema == nil || schema.Object == nil {
+		return nil, nil
+	}
+	owners := schema.GetAll("network-owner").Fields()
+	if len(owners) == 0 {
+		return nil, nil
+	}
+
+	_, ipnet, err := net.ParseCIDR(dom.Name())
+	if err != nil {
+		return nil, nil
+	}
+	ones, bits := ipnet.Mask.Size()
+
+	for n := ones; n >= 0; n-- {
+		mask := net.CIDRMask(n, bits)
+		name := (&net.IPNet{IP: ipnet.IP.Mask(mask), Mask: mask}).String()
+
+		for _, owner := range owners {
+			if owner == dom.Schema() && n == ones {
+				continue
+			}
+
+			parent, err := a.fetch.LoadObject(owner, name)
+			if err == NotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			a.schemas.Apply(parent)
+
+			return parent, nil
+		}
+	}
+
+	return nil, nil
+}
+
+// asBlock returns the as-block containing the aut-num dom. The fetcher must
+// be a Lister for as-blocks to be found.
+func (a *Authoriser) asBlock(dom *Object) (*Object, error) {
+	list, ok := a.fetch.(Lister)
+	if !ok {
+		return nil, nil
+	}
+	asn, ok := parseASN(dom.Name())
+	if !ok {
+		return nil, nil
+	}
+
+	lis, err := list.ListObjects("as-block")
+	if err != nil {
+		return nil, err
+	}
+
+	var parent *Object
+	var size uint64
+	for _, block := range lis {
+		bounds := strings.SplitN(block.Get("as-block").Text(), "-", 2)
+		if len(bounds) != 2 {
+			continue
+		}
+		first, ok1 := parseASN(bounds[0])
+		last, ok2 := parseASN(bounds[1])
+		if !ok1 || !ok2 || asn < first || asn > last {
+			continue
+		}
+		if parent == nil || last-first < size {
+			parent, size = block, last-first
+		}
+	}
+	if parent != nil {
+		a.schemas.Apply(parent)
+	}
+
+	return parent, nil
+}
+
+// parseASN parses an AS number such as AS4242420000.
+func parseASN(s string) (uint64, bool) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if !strings.HasPrefix(s, "AS") {
+		return 0, false
+	}
+	n, err := strconv.ParseUint(s[2:], 10, 32)
+	return n, err == nil
+}
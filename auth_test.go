@@ -0,0 +1,102 @@
+package rpsl_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestAuthorise(t *testing.T) {
+	is := is.New(t)
+
+	f, err := os.Open("schema.txt")
+	is.NoErr(err)
+	defer f.Close()
+	lis := rpsl.ParseAll(f)
+	schemas, err := rpsl.ParseSchemas(lis)
+	is.NoErr(err)
+
+	lis = append(lis, rpsl.ParseAll(strings.NewReader(cleanDoc(txtDN42Objects+`
+        mntner:     XUU-MNT
+        mnt-by:     XUU-MNT
+        source:     DN42
+
+        mntner:     FOO-MNT
+        mnt-by:     FOO-MNT
+        source:     DN42
+
+        inetnum:    172.20.0.0 - 172.23.255.255
+        cidr:       172.20.0.0/14
+        mnt-by:     DN42-MNT
+        mnt-lower:  XUU-MNT
+        mnt-lower:  MISSING-MNT
+        mnt-routes: FOO-MNT
+        source:     DN42
+
+        as-block:   AS4242420000-AS4242423999
+        policy:     open
+        mnt-by:     DN42-MNT
+        mnt-lower:  XUU-MNT
+        source:     DN42
+        `)))...)
+	r := rpsl.NewRPSL(rpsl.WithRPSLDir(writeRegistry(t, lis)))
+	a := rpsl.NewAuthoriser(r, schemas)
+
+	inetnum := rpsl.ParseObject("inetnum: 172.20.1.0 - 172.20.1.255\ncidr: 172.20.1.0/24\nmnt-by: XUU-MNT\nsource: DN42")
+	d, err := a.Authorise(nil, inetnum, "XUU-MNT")
+	is.NoErr(err)
+	is.True(d.Allow)
+	is.Equal(d.String(), "allow: parent is inetnum 172.20.0.0/14; mnt-lower MISSING-MNT does not resolve; create inetnum 172.20.1.0/24: signed by XUU-MNT listed in mnt-lower of inetnum 172.20.0.0/14")
+
+	d, err = a.Authorise(nil, inetnum, "FOO-MNT")
+	is.NoErr(err)
+	is.True(!d.Allow)
+	is.Equal(d.Reasons[1], "mnt-lower MISSING-MNT does not resolve")
+	is.Equal(d.Reasons[2], "create inetnum 172.20.1.0/24: requires a signature from mnt-lower of inetnum 172.20.0.0/14: XUU-MNT")
+
+	route := rpsl.ParseObject("route: 172.20.1.0/24\norigin: AS4242420000\nmnt-by: FOO-MNT\nsource: DN42")
+	d, err = a.Authorise(nil, route, "FOO-MNT")
+	is.NoErr(err)
+	is.True(d.Allow)
+
+	// Without mnt-lower the parent mnt-by is used.
+	d, err = a.Authorise(nil, rpsl.ParseObject("inetnum: 10.0.0.0 - 10.255.255.255\ncidr: 10.0.0.0/8\nmnt-by: XUU-MNT"), "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(d.String(), "deny: parent is inetnum 0.0.0.0/0; create inetnum 10.0.0.0/8: requires a signature from mnt-by of inetnum 0.0.0.0/0: DN42-MNT")
+
+	mnt, err := r.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	d, err = a.Authorise(mnt, mnt, "FOO-MNT", "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(d.String(), "allow: modify mntner XUU-MNT: signed by XUU-MNT listed in mnt-by of mntner XUU-MNT")
+
+	d, err = a.Authorise(mnt, nil, "FOO-MNT")
+	is.NoErr(err)
+	is.Equal(d.String(), "deny: delete mntner XUU-MNT: requires a signature from mnt-by of mntner XUU-MNT: XUU-MNT")
+
+	d, err = a.Authorise(nil, rpsl.ParseObject("person: Foo\nnic-hdl: FOO-DN42\nmnt-by: FOO-MNT"), "FOO-MNT")
+	is.NoErr(err)
+	is.True(d.Allow)
+
+	// Aut-nums are created within an as-block.
+	autnum := rpsl.ParseObject("aut-num: AS4242420001\nas-name: FOO\nmnt-by: FOO-MNT\nsource: DN42")
+	d, err = a.Authorise(nil, autnum, "FOO-MNT")
+	is.NoErr(err)
+	is.Equal(d.String(), "deny: parent is as-block AS4242420000-AS4242423999; create aut-num AS4242420001: requires a signature from mnt-lower of as-block AS4242420000-AS4242423999: XUU-MNT")
+
+	d, err = a.Authorise(nil, autnum, "XUU-MNT")
+	is.NoErr(err)
+	is.True(d.Allow)
+
+	// Without a parent outsiders can't create objects by naming their own mnt-by.
+	d, err = a.Authorise(nil, rpsl.ParseObject("aut-num: AS65000\nas-name: FOO\nmnt-by: FOO-MNT\nsource: DN42"), "FOO-MNT")
+	is.NoErr(err)
+	is.Equal(d.String(), "deny: create aut-num AS65000: no parent object")
+
+	d, err = a.Authorise(nil, rpsl.ParseObject("inet6num: fd00:: - fdff:ffff:ffff:ffff:ffff:ffff:ffff:ffff\ncidr: fd00::/8\nmnt-by: FOO-MNT\nsource: DN42"), "FOO-MNT")
+	is.NoErr(err)
+	is.Equal(d.String(), "deny: create inet6num fd00::/8: no parent object")
+}
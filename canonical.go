@@ -0,0 +1,76 @@
+package rpsl
+
+import (
+	"hash"
+	"strings"
+)
+
+// Canonical returns the object in a form suitable for hashing and signing.
+// Attributes keep their order, names are lowercased and separated from the
+// value by a single space. Comments are stripped and whitespace within values
+// is collapsed. Continuation lines are indented by one space, blank ones are
+// written as "+" and trailing blank lines are dropped. Every line ends with a
+// newline.
+func (dom *Object) Canonical() string {
+	var b strings.Builder
+	for _, attr := range dom.attributes {
+		attr.canonical(&b)
+	}
+
+	return b.String()
+}
+
+func (attr *Attribute) canonical(b *strings.Builder) {
+	var lines []string
+	for i, row := range attr.rows {
+		value, comment := row.split()
+		if value == "" && comment != "" && i > 0 {
+			continue
+		}
+		lines = append(lines, strings.Join(strings.Fields(value), " "))
+	}
+	for len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+
+	b.WriteString(strings.ToLower(attr.Name))
+	b.WriteByte(':')
+	for i, line := range lines {
+		switch {
+		case i == 0 && line != "":
+			b.WriteByte(' ')
+		case i > 0 && line == "":
+			b.WriteString("\n+")
+		case i > 0:
+			b.WriteString("\n ")
+		}
+		b.WriteString(line)
+	}
+	b.WriteByte('\n')
+}
+
+// Digest returns the hash of the canonical form of the object.
+func (dom *Object) Digest(h hash.Hash) []byte {
+	h.Reset()
+	h.Write([]byte(dom.Canonical()))
+
+	return h.Sum(nil)
+}
+
+// Canonical returns the canonical forms of the objects separated by blank lines.
+func (lis ListObject) Canonical() string {
+	s := make([]string, len(lis))
+	for i, dom := range lis {
+		s[i] = dom.Canonical()
+	}
+
+	return strings.Join(s, "\n")
+}
+
+// Digest returns the hash of the canonical form of the objects.
+func (lis ListObject) Digest(h hash.Hash) []byte {
+	h.Reset()
+	h.Write([]byte(lis.Canonical()))
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,50 @@
+package rpsl_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestCanonical(t *testing.T) {
+	is := is.New(t)
+
+	a := rpsl.ParseObject(cleanDoc(`
+        person:      Foo   Bar  # display name
+        nic-hdl:     FOO-DN42
+        remarks:     first
+                     # only a comment
+        +
+                     second    line
+        +
+        source:      DN42
+        `))
+	b := rpsl.ParseObject("person: Foo Bar\nnic-hdl: FOO-DN42\nremarks: first\n+\n second line\nsource: DN42")
+
+	is.Equal(a.Canonical(), "person: Foo Bar\nnic-hdl: FOO-DN42\nremarks: first\n+\n second line\nsource: DN42\n")
+	is.Equal(a.Canonical(), b.Canonical())
+	is.Equal(a.Digest(sha256.New()), b.Digest(sha256.New()))
+
+	rpsl.PadLength = 30
+	defer func() { rpsl.PadLength = 19 }()
+	is.Equal(a.Canonical(), b.Canonical())
+
+	b.Add("remarks", "changed")
+	is.True(hex.EncodeToString(a.Digest(sha256.New())) != hex.EncodeToString(b.Digest(sha256.New())))
+
+	lis := rpsl.ListObject{a, rpsl.ParseObject("mntner: FOO-MNT\nEmpty:")}
+	is.Equal(lis.Canonical(), a.Canonical()+"\nmntner: FOO-MNT\nempty:\n")
+
+	// Deleted attributes are skipped.
+	c := rpsl.ParseObject("person: Foo Bar\nremarks: gone\nnic-hdl: FOO-DN42\nremarks: first\n+\n second line\nsource: DN42")
+	c.Delete("remarks")
+	is.Equal(c.Canonical(), a.Canonical())
+	is.Equal(c.Digest(sha256.New()), a.Digest(sha256.New()))
+	is.Equal(len(c.GetAll("remarks")), 1)
+	for _, attr := range c.Attrs() {
+		_ = attr.Text()
+	}
+}
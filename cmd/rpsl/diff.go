@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+func init() {
+	commands["diff"] = command{"list objects changed between two registries", runDiff}
+}
+
+func runDiff(args []string) error {
+	fs := flag.NewFlagSet("diff", flag.ExitOnError)
+	format := fs.String("format", "text", "output format: text, json or unified")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rpsl diff [flags] <old data dir> <new data dir>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	old := rpsl.NewRPSL(rpsl.WithRPSLDir(fs.Arg(0)))
+	new := rpsl.NewRPSL(rpsl.WithRPSLDir(fs.Arg(1)))
+
+	d, err := rpsl.DiffTree(old, new)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "text":
+		_, err = fmt.Print(d)
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(d)
+	case "unified":
+		for _, c := range d.Objects {
+			switch c.Op {
+			case rpsl.DiffModify, rpsl.DiffMove:
+				_, err = fmt.Print(c.Diff)
+			default:
+				_, err = fmt.Print(rpsl.Diff(c.Old, c.New))
+			}
+			if err != nil {
+				return err
+			}
+		}
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	return err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+func init() {
+	commands["dump"] = command{"write a registry to a single dump file", runDump}
+}
+
+func runDump(args []string) error {
+	fs := flag.NewFlagSet("dump", flag.ExitOnError)
+	serial := fs.Uint64("serial", 0, "serial `number` written in the header")
+	compress := fs.Bool("z", false, "gzip compress the dump")
+	out := fs.String("o", "", "output `file` (default stdout)")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rpsl dump [flags] <registry data dir>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var w io.Writer = os.Stdout
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	r := rpsl.NewRPSL(rpsl.WithRPSLDir(fs.Arg(0)))
+	hdr := rpsl.DumpHeader{Serial: *serial, Time: time.Now()}
+
+	return rpsl.WriteDump(w, r, hdr, *compress)
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/expand"
+	"rpsl.dn42.us/go-rpsl/filter"
+)
+
+func init() {
+	commands["filter"] = command{"generate prefix filters for an aut-num or set", runFilter}
+}
+
+func runFilter(args []string) error {
+	fs := flag.NewFlagSet("filter", flag.ExitOnError)
+	format := fs.String("format", "bird", "output format: bird, cisco, juniper or json")
+	name := fs.String("name", "", "name of the generated list")
+	ipv6 := fs.Bool("6", false, "generate an IPv6 filter")
+	aggregate := fs.Bool("A", false, "aggregate prefixes")
+	maxLength := fs.Int("R", 0, "permit more specifics up to this length")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rpsl filter [flags] <registry data dir> <aut-num or set>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	r := rpsl.NewRPSL(rpsl.WithRPSLDir(fs.Arg(0)))
+	lis, err := filter.Generate(expand.New(r), fs.Arg(1), filter.Options{
+		Name:      *name,
+		IPv6:      *ipv6,
+		Aggregate: *aggregate,
+		MaxLength: *maxLength,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, name := range lis.Unresolved {
+		fmt.Fprintln(os.Stderr, "rpsl: unresolved set", name)
+	}
+
+	switch *format {
+	case "bird":
+		return lis.WriteBird(os.Stdout)
+	case "cisco":
+		return lis.WriteCisco(os.Stdout)
+	case "juniper":
+		return lis.WriteJuniper(os.Stdout)
+	case "json":
+		return lis.WriteJSON(os.Stdout)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+func init() {
+	commands["fmt"] = command{"format objects and sort attributes into schema order", runFmt}
+}
+
+func runFmt(args []string) error {
+	fs := flag.NewFlagSet("fmt", flag.ExitOnError)
+	dir := fs.String("registry", "", "registry data `dir` to read schemas from")
+	write := fs.Bool("w", false, "write result to the files instead of stdout")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rpsl fmt [flags] [file ...]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *write && fs.NArg() == 0 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var schemaObjects rpsl.ListObject
+	if *dir != "" {
+		lis, err := rpsl.NewRPSL(rpsl.WithRPSLDir(*dir)).ListObjects("schema")
+		if err != nil {
+			return err
+		}
+		schemaObjects = lis
+	}
+
+	format := func(in io.Reader) ([]byte, error) {
+		lis := rpsl.ParseAll(in)
+
+		schemas, err := rpsl.ParseSchemas(append(append(rpsl.ListObject(nil), schemaObjects...), lis...))
+		if err != nil {
+			return nil, err
+		}
+		schemas.Apply(lis...)
+		for _, dom := range lis {
+			dom.Normalize()
+		}
+
+		return []byte(lis.String() + "\n"), nil
+	}
+
+	if fs.NArg() == 0 {
+		b, err := format(os.Stdin)
+		if err != nil {
+			return err
+		}
+		_, err = os.Stdout.Write(b)
+		return err
+	}
+
+	for _, name := range fs.Args() {
+		src, err := os.ReadFile(name)
+		if err != nil {
+			return err
+		}
+		b, err := format(bytes.NewReader(src))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+
+		if !*write {
+			if _, err := os.Stdout.Write(b); err != nil {
+				return err
+			}
+			continue
+		}
+		if !bytes.Equal(src, b) {
+			if err := os.WriteFile(name, b, 0644); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
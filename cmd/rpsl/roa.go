@@ -0,0 +1,66 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/roa"
+)
+
+func init() {
+	commands["roa"] = command{"generate roa tables from route objects", runROA}
+}
+
+func runROA(args []string) error {
+	fs := flag.NewFlagSet("roa", flag.ExitOnError)
+	format := fs.String("format", "bird", "output format: bird, json or openbgpd")
+	only4 := fs.Bool("4", false, "only output IPv4 entries")
+	only6 := fs.Bool("6", false, "only output IPv6 entries")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rpsl roa [flags] <registry data dir>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	r := rpsl.NewRPSL(rpsl.WithRPSLDir(fs.Arg(0)))
+
+	var lis rpsl.ListObject
+	for _, schema := range []string{"route", "route6"} {
+		routes, err := r.ListObjects(schema)
+		if err != nil {
+			return err
+		}
+		lis = append(lis, routes...)
+	}
+
+	tbl, err := roa.FromObjects(lis)
+	if err != nil {
+		// Bad routes are left out rather than stopping the table.
+		fmt.Fprintln(os.Stderr, "rpsl:", err)
+	}
+
+	switch {
+	case *only4:
+		tbl = tbl.IPv4()
+	case *only6:
+		tbl = tbl.IPv6()
+	}
+
+	switch *format {
+	case "bird":
+		return tbl.WriteBird(os.Stdout)
+	case "json":
+		return tbl.WriteJSON(os.Stdout)
+	case "openbgpd":
+		return tbl.WriteOpenBGPD(os.Stdout)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
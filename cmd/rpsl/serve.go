@@ -0,0 +1,100 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/api"
+	"rpsl.dn42.us/go-rpsl/rdap"
+)
+
+func init() {
+	commands["serve"] = command{"serve registry objects over HTTP and RDAP", runServe}
+}
+
+func runServe(args []string) error {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", ":8080", "listen `address`")
+	watch := fs.Bool("watch", false, "load a data directory into memory and reload changed files")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rpsl serve [flags] <registry data dir or dump file>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	var (
+		r   *rpsl.RPSL
+		err error
+	)
+	if *watch {
+		r, err = watchRegistry(fs.Arg(0))
+	} else {
+		r, err = openRegistry(fs.Arg(0))
+	}
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(api.Prefix, api.New(r))
+	mux.Handle(rdap.Prefix, rdap.New(r))
+
+	return http.ListenAndServe(*addr, mux)
+}
+
+// openRegistry loads a dump file into memory or reads from a data directory.
+func openRegistry(path string) (*rpsl.RPSL, error) {
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if fi.IsDir() {
+		return rpsl.NewRPSL(rpsl.WithRPSLDir(path)), nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	_, m, err := rpsl.ReadDump(f)
+	if err != nil {
+		return nil, err
+	}
+
+	return rpsl.NewRPSL(rpsl.WithFetcher(m)), nil
+}
+
+// watchRegistry loads a data directory into memory and applies changes to its
+// files as they are made.
+func watchRegistry(dir string) (*rpsl.RPSL, error) {
+	m, err := rpsl.LoadDir(context.Background(), dir, 0)
+	if err != nil {
+		return nil, err
+	}
+
+	w := rpsl.NewWatcher(dir, m)
+	go func() {
+		for e := range w.Events() {
+			log.Printf("%s %s %s", e.Type, e.Schema, e.Name)
+		}
+	}()
+	go func() {
+		if err := w.Run(context.Background()); err != nil {
+			log.Fatal(err)
+		}
+	}()
+
+	return rpsl.NewRPSL(rpsl.WithFetcher(m)), nil
+}
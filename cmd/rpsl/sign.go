@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/signature"
+)
+
+func init() {
+	commands["sign"] = command{"append an ssh signature to objects or a transaction", runSign}
+}
+
+func runSign(args []string) error {
+	fs := flag.NewFlagSet("sign", flag.ExitOnError)
+	keyFile := fs.String("key", "", "ed25519 or rsa private key `file`")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rpsl sign -key <private key> [file]")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if *keyFile == "" || fs.NArg() > 1 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	b, err := os.ReadFile(*keyFile)
+	if err != nil {
+		return err
+	}
+	key, err := signature.ParsePrivateKey(b)
+	if err != nil {
+		return err
+	}
+
+	var in io.Reader = os.Stdin
+	if fs.NArg() == 1 {
+		f, err := os.Open(fs.Arg(0))
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	lis, err := signature.Append(rpsl.ParseAll(in), key)
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Println(lis.String())
+	return err
+}
@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/zone"
+)
+
+func init() {
+	commands["zone"] = command{"generate zone delegations from dns and inetnum objects", runZone}
+}
+
+func runZone(args []string) error {
+	fs := flag.NewFlagSet("zone", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: rpsl zone <registry data dir> <origin>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+
+	if fs.NArg() != 2 {
+		fs.Usage()
+		os.Exit(2)
+	}
+
+	r := rpsl.NewRPSL(rpsl.WithRPSLDir(fs.Arg(0)))
+
+	var lis rpsl.ListObject
+	for _, schema := range []string{"dns", "inetnum", "inet6num"} {
+		objects, err := r.ListObjects(schema)
+		if err != nil {
+			return err
+		}
+		lis = append(lis, objects...)
+	}
+
+	z, err := zone.Generate(fs.Arg(1), lis)
+	if err != nil {
+		// Bad objects are left out rather than stopping the zone.
+		fmt.Fprintln(os.Stderr, "rpsl:", err)
+	}
+
+	return z.WriteBind(os.Stdout)
+}
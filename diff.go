@@ -0,0 +1,398 @@
+package rpsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOp is the kind of change made to an attribute or row.
+type DiffOp int
+
+const (
+	DiffEqual DiffOp = iota
+	DiffAdd
+	DiffRemove
+	DiffModify
+	DiffMove
+)
+
+var diffOps = []string{"equal", "add", "remove", "modify", "move"}
+
+func (op DiffOp) String() string {
+	if op >= 0 && int(op) < len(diffOps) {
+		return diffOps[op]
+	}
+	return fmt.Sprintf("DiffOp(%d)", int(op))
+}
+
+// MarshalText encodes the op by name.
+func (op DiffOp) MarshalText() ([]byte, error) {
+	return []byte(op.String()), nil
+}
+
+// UnmarshalText decodes an op name.
+func (op *DiffOp) UnmarshalText(b []byte) error {
+	for i, name := range diffOps {
+		if string(b) == name {
+			*op = DiffOp(i)
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown diff op %q", b)
+}
+
+// RowChange is a change to a single row of a modified attribute.
+type RowChange struct {
+	Op   DiffOp `json:"op"`
+	Text string `json:"text"`
+}
+
+// AttrChange is a change to an attribute. OldIndex and NewIndex are the
+// positions of the attribute in the old and new object, or -1 when the
+// attribute is added or removed. Old and New hold the rows of the attribute
+// including comments. Rows holds the row changes of a modification.
+type AttrChange struct {
+	Op       DiffOp      `json:"op"`
+	Name     string      `json:"name"`
+	OldIndex int         `json:"old_index"`
+	NewIndex int         `json:"new_index"`
+	Old      []string    `json:"old,omitempty"`
+	New      []string    `json:"new,omitempty"`
+	Rows     []RowChange `json:"rows,omitempty"`
+}
+
+// ObjectDiff lists the attribute changes between two versions of an object.
+type ObjectDiff struct {
+	Schema  string        `json:"schema"`
+	Name    string        `json:"name"`
+	Changes []*AttrChange `json:"changes"`
+
+	old []*Attribute
+}
+
+// Empty reports whether the objects were equal.
+func (d *ObjectDiff) Empty() bool {
+	return len(d.Changes) == 0
+}
+
+// Diff compares the attributes of objects a and b. Attributes are matched on
+// name and value. Within a run of unmatched attributes, removed and added
+// attributes of the same name pair up as modifications with row changes. Any
+// remaining attribute that was removed in one place and added in another is a
+// move.
+func Diff(a, b *Object) *ObjectDiff {
+	oldAttrs, newAttrs := a.present(), b.present()
+	d := &ObjectDiff{Schema: b.Schema(), Name: b.Name(), old: oldAttrs}
+	if b == nil || len(newAttrs) == 0 {
+		d.Schema, d.Name = a.Schema(), a.Name()
+	}
+
+	oldKeys := make([]string, len(oldAttrs))
+	for i, attr := range oldAttrs {
+		oldKeys[i] = attr.Name + "\x00" + attr.Raw()
+	}
+	newKeys := make([]string, len(newAttrs))
+	for i, attr := range newAttrs {
+		newKeys[i] = attr.Name + "\x00" + attr.Raw()
+	}
+
+	var removed, added []*AttrChange
+	flush := func() {
+		for _, r := range removed {
+			for j, c := range added {
+				if c.Name != r.Name {
+					continue
+				}
+				r.Op, r.NewIndex, r.New = DiffModify, c.NewIndex, c.New
+				r.Rows = diffRows(r.Old, r.New)
+				added = append(added[:j], added[j+1:]...)
+				break
+			}
+			d.Changes = append(d.Changes, r)
+		}
+		d.Changes = append(d.Changes, added...)
+		removed, added = nil, nil
+	}
+
+	for _, e := range lcs(oldKeys, newKeys) {
+		switch e.op {
+		case DiffEqual:
+			flush()
+		case DiffRemove:
+			attr := oldAttrs[e.i]
+			removed = append(removed, &AttrChange{Op: DiffRemove, Name: attr.Name, OldIndex: e.i, NewIndex: -1, Old: attr.rowStrings()})
+		case DiffAdd:
+			attr := newAttrs[e.j]
+			added = append(added, &AttrChange{Op: DiffAdd, Name: attr.Name, OldIndex: -1, NewIndex: e.j, New: attr.rowStrings()})
+		}
+	}
+	flush()
+
+	// Pair removals and additions of identical attributes as moves.
+	var lis []*AttrChange
+	for _, c := range d.Changes {
+		if c.Op != DiffAdd {
+			lis = append(lis, c)
+			continue
+		}
+
+		moved := false
+		for _, r := range lis {
+			if r.Op == DiffRemove && r.Name == c.Name && oldKeys[r.OldIndex] == newKeys[c.NewIndex] {
+				r.Op, r.NewIndex, r.New = DiffMove, c.NewIndex, c.New
+				moved = true
+				break
+			}
+		}
+		if !moved {
+			lis = append(lis, c)
+		}
+	}
+	d.Changes = lis
+
+	return d
+}
+
+// Patch applies diff d to dom and returns the resulting object. The
+// attributes of dom must match those the diff was made from. A nil dom patches
+// the diff of a created object.
+func Patch(dom *Object, d *ObjectDiff) (*Object, error) {
+	attrs := dom.present()
+
+	changed := make(map[int]bool)
+	placed := make(map[int]*AttrChange)
+	size := len(attrs)
+	for _, c := range d.Changes {
+		if c.OldIndex >= 0 {
+			if c.OldIndex >= len(attrs) {
+				return nil, fmt.Errorf("patch: attribute %d out of range", c.OldIndex)
+			}
+			attr := attrs[c.OldIndex]
+			if attr.Name != c.Name || attr.Raw() != strings.Join(c.Old, "\n") {
+				return nil, fmt.Errorf("patch: attribute %d is %s, expected %s", c.OldIndex, attr.Name, c.Name)
+			}
+			changed[c.OldIndex] = true
+			size--
+		}
+		if c.NewIndex >= 0 {
+			placed[c.NewIndex] = c
+			size++
+		}
+	}
+
+	out := &Object{keys: make(map[string][]int)}
+	if dom != nil {
+		out.schema = dom.schema
+	}
+	next := 0
+	for i := 0; i < size; i++ {
+		if c, ok := placed[i]; ok {
+			out.Add(c.Name, c.New...)
+			continue
+		}
+
+		for next < len(attrs) && changed[next] {
+			next++
+		}
+		if next == len(attrs) {
+			return nil, fmt.Errorf("patch: attribute %d missing", i)
+		}
+		out.attributes = append(out.attributes, attrs[next])
+		out.keys[attrs[next].Name] = append(out.keys[attrs[next].Name], len(out.attributes)-1)
+		next++
+	}
+
+	return out, nil
+}
+
+// String renders the diff as a unified diff. When the diff was created by Diff
+// unchanged attributes are included for context.
+func (d *ObjectDiff) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "--- a/%s/%s\n+++ b/%s/%s\n", d.Schema, FileName(d.Name), d.Schema, FileName(d.Name))
+
+	pad := PadLength
+	for _, c := range d.Changes {
+		if len(c.Name) > pad {
+			pad = len(c.Name) + 2
+		}
+	}
+	line := func(prefix, name string, rows []string) {
+		b.WriteString(prefixLines(prefix, NewAttribute(name, rows...).StringN(pad)))
+		b.WriteByte('\n')
+	}
+	modify := func(c *AttrChange) {
+		for _, s := range modifyLines(c, pad) {
+			b.WriteString(s)
+			b.WriteByte('\n')
+		}
+	}
+
+	if d.old == nil {
+		for _, c := range d.Changes {
+			switch c.Op {
+			case DiffModify:
+				modify(c)
+			case DiffRemove:
+				line("-", c.Name, c.Old)
+			case DiffAdd:
+				line("+", c.Name, c.New)
+			case DiffMove:
+				line("-", c.Name, c.Old)
+				line("+", c.Name, c.New)
+			}
+		}
+		return b.String()
+	}
+
+	byOld := make(map[int]*AttrChange)
+	byNew := make(map[int]*AttrChange)
+	size := len(d.old)
+	for _, c := range d.Changes {
+		if c.OldIndex >= 0 {
+			byOld[c.OldIndex] = c
+			size--
+		}
+		if c.NewIndex >= 0 {
+			byNew[c.NewIndex] = c
+			size++
+		}
+	}
+
+	// Removals are shown before additions, then unchanged or modified
+	// attributes advance both positions.
+	for i, j := 0, 0; i < len(d.old) || j < size; {
+		if c, ok := byOld[i]; ok && c.Op != DiffModify {
+			line("-", c.Name, c.Old)
+			i++
+			continue
+		}
+		if c, ok := byNew[j]; ok && c.Op != DiffModify {
+			line("+", c.Name, c.New)
+			j++
+			continue
+		}
+		if i >= len(d.old) {
+			break
+		}
+
+		if c, ok := byOld[i]; ok {
+			modify(c)
+		} else {
+			line(" ", d.old[i].Name, d.old[i].rowStrings())
+		}
+		i++
+		j++
+	}
+
+	return b.String()
+}
+
+// modifyLines renders row changes, naming the attribute on the first row of
+// the old and new versions.
+func modifyLines(c *AttrChange, pad int) []string {
+	var out []string
+	oldFirst, newFirst := true, true
+	for _, row := range c.Rows {
+		prefix, first := " ", oldFirst && newFirst
+		switch row.Op {
+		case DiffAdd:
+			prefix, first = "+", newFirst
+			newFirst = false
+		case DiffRemove:
+			prefix, first = "-", oldFirst
+			oldFirst = false
+		default:
+			oldFirst, newFirst = false, false
+		}
+
+		switch {
+		case first:
+			out = append(out, prefix+c.Name+":"+strings.Repeat(" ", pad-len(c.Name))+row.Text)
+		case row.Text == "":
+			out = append(out, prefix+"+")
+		default:
+			out = append(out, prefix+strings.Repeat(" ", pad+1)+row.Text)
+		}
+	}
+	return out
+}
+
+func prefixLines(prefix, s string) string {
+	return prefix + strings.ReplaceAll(s, "\n", "\n"+prefix)
+}
+
+// present returns the attributes of the object, which may be nil.
+func (dom *Object) present() []*Attribute {
+	if dom == nil {
+		return nil
+	}
+	return dom.attributes
+}
+
+func (attr *Attribute) rowStrings() []string {
+	lis := make([]string, len(attr.rows))
+	for i, row := range attr.rows {
+		lis[i] = row.String()
+	}
+	return lis
+}
+
+func diffRows(a, b []string) []RowChange {
+	var lis []RowChange
+	for _, e := range lcs(a, b) {
+		switch e.op {
+		case DiffRemove:
+			lis = append(lis, RowChange{Op: DiffRemove, Text: a[e.i]})
+		default:
+			lis = append(lis, RowChange{Op: e.op, Text: b[e.j]})
+		}
+	}
+	return lis
+}
+
+type edit struct {
+	op   DiffOp
+	i, j int
+}
+
+// lcs returns the edits turning a into b along a longest common subsequence.
+// Removals are ordered before additions within a run of changes.
+func lcs(a, b []string) []edit {
+	n, m := len(a), len(b)
+	table := make([][]int, n+1)
+	for i := range table {
+		table[i] = make([]int, m+1)
+	}
+	for i := n - 1; i >= 0; i-- {
+		for j := m - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				table[i][j] = table[i+1][j+1] + 1
+			case table[i+1][j] >= table[i][j+1]:
+				table[i][j] = table[i+1][j]
+			default:
+				table[i][j] = table[i][j+1]
+			}
+		}
+	}
+
+	var lis []edit
+	i, j := 0, 0
+	for i < n || j < m {
+		switch {
+		case i < n && j < m && a[i] == b[j]:
+			lis = append(lis, edit{DiffEqual, i, j})
+			i++
+			j++
+		case i < n && (j == m || table[i+1][j] >= table[i][j+1]):
+			lis = append(lis, edit{DiffRemove, i, j})
+			i++
+		default:
+			lis = append(lis, edit{DiffAdd, i, j})
+			j++
+		}
+	}
+
+	return lis
+}
@@ -0,0 +1,98 @@
+package rpsl_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestDiff(t *testing.T) {
+	is := is.New(t)
+
+	a := rpsl.ParseObject(cleanDoc(`
+        person:             Xuu
+        contact:            xmpp:xuu@xmpp.dn42
+        contact:            mail:xuu@dn42.us
+        remarks:            test
+                            foo
+        nic-hdl:            XUU-DN42
+        mnt-by:             XUU-MNT
+        source:             DN42
+        `))
+	b := rpsl.ParseObject(cleanDoc(`
+        person:             Xuu
+        contact:            mail:xuu@dn42.us
+        contact:            xmpp:xuu@xmpp.dn42
+        remarks:            test
+                            bar
+        nic-hdl:            XUU-DN42
+        mnt-by:             XUU-MNT
+        mnt-by:             FOO-MNT
+        source:             DN42
+        `))
+
+	d := rpsl.Diff(a, b)
+	is.Equal(d.Schema, "person")
+	is.Equal(d.Name, "Xuu")
+	is.Equal(len(d.Changes), 3)
+	is.Equal(d.Changes[0].Op, rpsl.DiffMove)
+	is.Equal(d.Changes[0].OldIndex, 1)
+	is.Equal(d.Changes[0].NewIndex, 2)
+	is.Equal(d.Changes[1].Op, rpsl.DiffModify)
+	is.Equal(d.Changes[1].Rows, []rpsl.RowChange{{rpsl.DiffEqual, "test"}, {rpsl.DiffRemove, "foo"}, {rpsl.DiffAdd, "bar"}})
+	is.Equal(d.Changes[2].Op, rpsl.DiffAdd)
+	is.Equal(d.Changes[2].New, []string{"FOO-MNT"})
+
+	is.Equal(d.String(), cleanDoc(`
+        --- a/person/Xuu
+        +++ b/person/Xuu
+         person:             Xuu
+        -contact:            xmpp:xuu@xmpp.dn42
+         contact:            mail:xuu@dn42.us
+        +contact:            xmpp:xuu@xmpp.dn42
+         remarks:            test
+        -                    foo
+        +                    bar
+         nic-hdl:            XUU-DN42
+         mnt-by:             XUU-MNT
+        +mnt-by:             FOO-MNT
+         source:             DN42
+        `)+"\n")
+
+	patched, err := rpsl.Patch(a, d)
+	is.NoErr(err)
+	is.Equal(patched.String(), b.String())
+
+	// Diffs survive a round trip through JSON.
+	buf, err := json.Marshal(d)
+	is.NoErr(err)
+	var decoded rpsl.ObjectDiff
+	is.NoErr(json.Unmarshal(buf, &decoded))
+	patched, err = rpsl.Patch(a, &decoded)
+	is.NoErr(err)
+	is.Equal(patched.String(), b.String())
+	is.Equal(decoded.String(), cleanDoc(`
+        --- a/person/Xuu
+        +++ b/person/Xuu
+        -contact:            xmpp:xuu@xmpp.dn42
+        +contact:            xmpp:xuu@xmpp.dn42
+         remarks:            test
+        -                    foo
+        +                    bar
+        +mnt-by:             FOO-MNT
+        `)+"\n")
+
+	// Patching an object that differs from the original fails.
+	_, err = rpsl.Patch(b, d)
+	is.True(err != nil)
+
+	is.True(rpsl.Diff(a, a).Empty())
+
+	d = rpsl.Diff(nil, b)
+	is.Equal(len(d.Changes), 8)
+	patched, err = rpsl.Patch(nil, d)
+	is.NoErr(err)
+	is.Equal(patched.String(), b.String())
+}
@@ -0,0 +1,261 @@
+package rpsl
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Lister lists all objects stored for a schema.
+type Lister interface {
+	ListObjects(schema string) (ListObject, error)
+}
+
+type optionFunc func(*RPSL)
+
+func (fn optionFunc) Apply(r *RPSL) { fn(r) }
+
+// WithRPSLDir reads objects from a registry data directory laid out as
+// <schema>/<name>. Schemas are loaded from the schema directory within.
+func WithRPSLDir(dir string) Option {
+	return WithFetcher(newDirFS(dir))
+}
+
+// WithFetcher reads objects using f. When f also implements Indexer or
+// Lister it is used for searching and listing.
+func WithFetcher(f Fetcher) Option {
+	return optionFunc(func(r *RPSL) {
+		r.fetch = f
+		if index, ok := f.(Indexer); ok {
+			r.index = index
+		}
+		if list, ok := f.(Lister); ok {
+			r.list = list
+		}
+	})
+}
+
+// LoadObject from the registry by schema and name.
+func (r *RPSL) LoadObject(schema, name string) (*Object, error) {
+	fetch, _, _ := r.backends()
+	return fetch.LoadObject(schema, name)
+}
+
+// FindObject from the registry matching search.
+func (r *RPSL) FindObject(search string) ([]*Object, error) {
+	_, index, _ := r.backends()
+	return index.FindObject(search)
+}
+
+// ListObjects in the registry for schema.
+func (r *RPSL) ListObjects(schema string) (ListObject, error) {
+	_, _, list := r.backends()
+	return list.ListObjects(schema)
+}
+
+// Schemas returns the schemas of the registry. Registries loaded in memory
+// return the schemas of their current snapshot, and directory and git
+// registries those loaded on first use, so the result follows Reload.
+func (r *RPSL) Schemas() (*Schemas, error) {
+	fetch, _, list := r.backends()
+	switch f := fetch.(type) {
+	case interface{ Schemas() *Schemas }:
+		return f.Schemas(), nil
+	case interface{ load() (*Schemas, error) }:
+		return f.load()
+	}
+
+	lis, err := list.ListObjects("schema")
+	if err != nil {
+		return nil, err
+	}
+	return ParseSchemas(lis)
+}
+
+// FileName converts an object name to the file name used in a registry directory.
+func FileName(name string) string {
+	return strings.ReplaceAll(name, "/", "_")
+}
+
+// source reads the files of a registry data directory.
+type source interface {
+	// readFile returns the contents of a file or NotFound.
+	readFile(schema, file string) ([]byte, error)
+
+	// readDir returns the names of files in a schema directory.
+	readDir(schema string) ([]string, error)
+}
+
+// registry reads objects from a source laid out as <schema>/<name>. Schemas
+// are loaded from the schema directory on first use and applied to every
+// object read.
+type registry struct {
+	src source
+
+	once    sync.Once
+	schemas *Schemas
+	err     error
+}
+
+func (r *registry) load() (*Schemas, error) {
+	r.once.Do(func() {
+		var lis ListObject
+		lis, r.err = r.parseDir("schema")
+		if r.err != nil {
+			return
+		}
+		r.schemas, r.err = ParseSchemas(lis)
+	})
+
+	return r.schemas, r.err
+}
+
+func (r *registry) parseFile(schema, file string) (*Object, error) {
+	b, err := r.src.readFile(schema, file)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseObject(string(b)), nil
+}
+
+func (r *registry) parseDir(schema string) (ListObject, error) {
+	names, err := r.src.readDir(schema)
+	if err != nil {
+		return nil, err
+	}
+
+	lis := make(ListObject, 0, len(names))
+	for _, name := range names {
+		dom, err := r.parseFile(schema, name)
+		if err != nil {
+			return nil, err
+		}
+		lis = append(lis, dom)
+	}
+
+	return lis, nil
+}
+
+func (r *registry) LoadObject(schema, name string) (*Object, error) {
+	schemas, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	dom, err := r.parseFile(schema, FileName(name))
+	if err != nil {
+		return nil, err
+	}
+	schemas.Apply(dom)
+
+	return dom, nil
+}
+
+func (r *registry) ListObjects(schema string) (ListObject, error) {
+	schemas, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	lis, err := r.parseDir(schema)
+	if err != nil {
+		return nil, err
+	}
+	schemas.Apply(lis...)
+
+	return lis, nil
+}
+
+// FindObject returns objects in any schema whose name matches search.
+func (r *registry) FindObject(search string) ([]*Object, error) {
+	schemas, err := r.load()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(schemas.m))
+	for name := range schemas.m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lis []*Object
+	for _, schema := range names {
+		dom, err := r.parseFile(schema, FileName(search))
+		if err == NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		schemas.Apply(dom)
+		lis = append(lis, dom)
+	}
+
+	if len(lis) == 0 {
+		return nil, NotFound
+	}
+
+	return lis, nil
+}
+
+// dirFS is a registry stored in a directory that can be written to.
+type dirFS struct {
+	*registry
+	root string
+}
+
+func newDirFS(root string) *dirFS {
+	return &dirFS{
+		registry: &registry{src: fsSource{os.DirFS(root)}},
+		root:     root,
+	}
+}
+
+// path returns the file of an object within the registry. Unknown schemas
+// and names that would resolve outside of the schema directory are rejected.
+func (fs *dirFS) path(schema, name string) (string, error) {
+	schemas, err := fs.load()
+	if err != nil {
+		return "", err
+	}
+	if schema != "schema" && schemas.Get(schema) == nil {
+		return "", fmt.Errorf("%s %s: unknown schema", schema, name)
+	}
+
+	file := FileName(name)
+	if !validFile(schema) || !validFile(file) {
+		return "", fmt.Errorf("%s %s: invalid object name", schema, name)
+	}
+
+	return filepath.Join(fs.root, schema, file), nil
+}
+
+func (fs *dirFS) SaveObject(dom *Object) error {
+	file, err := fs.path(dom.Schema(), dom.Name())
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	return os.WriteFile(file, []byte(dom.String()+"\n"), 0644)
+}
+
+func (fs *dirFS) DeleteObject(schema, name string) error {
+	file, err := fs.path(schema, name)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(file)
+	if os.IsNotExist(err) {
+		return NotFound
+	}
+	return err
+}
@@ -0,0 +1,120 @@
+package rpsl_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func writeRegistry(t *testing.T, lis rpsl.ListObject) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "rpsl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	for _, dom := range lis {
+		schema, name := dom.Schema(), dom.Get(dom.Schema()).Text()
+		if schema == "inetnum" || schema == "inet6num" {
+			name = dom.Get("cidr").Text()
+		}
+		if schema == "person" || schema == "role" {
+			name = dom.Get("nic-hdl").Text()
+		}
+		if schema == "schema" {
+			name = dom.Name()
+		}
+
+		path := filepath.Join(dir, schema, rpsl.FileName(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(dom.String()+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestDir(t *testing.T) {
+	is := is.New(t)
+
+	lis := rpsl.ParseAll(strings.NewReader(cleanDoc(txtAllObjects)))
+	dir := writeRegistry(t, lis)
+
+	r := rpsl.NewRPSL(rpsl.WithRPSLDir(dir))
+
+	mnt, err := r.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(mnt.Name(), "XUU-MNT")
+	is.Equal(mnt.Get("admin-c").Args().Get("lookup").String(), "nic-hdl/SOURIS-DN42")
+
+	net, err := r.LoadObject("inetnum", "172.21.64.0/29")
+	is.NoErr(err)
+	is.Equal(net.Primary(), "cidr")
+	is.Equal(net.Name(), "172.21.64.0/29")
+
+	_, err = r.LoadObject("mntner", "MISSING-MNT")
+	is.Equal(err, rpsl.NotFound)
+
+	all, err := r.ListObjects("mntner")
+	is.NoErr(err)
+	is.Equal(len(all), 2)
+	is.Equal(all[0].Name(), "DN42-MNT")
+	is.Equal(all[1].Name(), "XUU-MNT")
+
+	all, err = r.ListObjects("missing")
+	is.NoErr(err)
+	is.Equal(len(all), 0)
+
+	found, err := r.FindObject("SOURIS-DN42")
+	is.NoErr(err)
+	is.Equal(len(found), 1)
+	is.Equal(found[0].Schema(), "role")
+
+	_, err = r.FindObject("NOTHING")
+	is.Equal(err, rpsl.NotFound)
+
+	empty := rpsl.NewRPSL()
+	_, err = empty.LoadObject("mntner", "XUU-MNT")
+	is.Equal(err, rpsl.NotFound)
+}
+
+func TestDirSave(t *testing.T) {
+	is := is.New(t)
+
+	lis := rpsl.ParseAll(strings.NewReader(cleanDoc(txtAllObjects)))
+	dir := writeRegistry(t, lis)
+
+	r := rpsl.NewRPSL(rpsl.WithRPSLDir(dir))
+
+	mnt, err := r.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	mnt.Set("descr", "updated")
+	is.NoErr(r.SaveObject(mnt))
+
+	mnt, err = r.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(mnt.Get("descr").Text(), "updated")
+
+	// Names must stay within the registry.
+	is.True(r.SaveObject(rpsl.ParseObject("../escaped: pwned")) != nil)
+	is.True(r.SaveObject(rpsl.ParseObject("mntner: ..")) != nil)
+	is.True(r.SaveObject(rpsl.ParseObject("unknown: FOO")) != nil)
+	is.True(r.DeleteObject("..", "mntner") != nil)
+	is.True(r.DeleteObject("mntner", "..") != nil)
+
+	_, err = os.Stat(filepath.Join(dir, "..", "escaped"))
+	is.True(os.IsNotExist(err))
+
+	is.NoErr(r.DeleteObject("mntner", "XUU-MNT"))
+	is.Equal(r.DeleteObject("mntner", "XUU-MNT"), rpsl.NotFound)
+}
@@ -0,0 +1,139 @@
+package rpsl
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DumpHeader describes the state of a registry written to a dump.
+type DumpHeader struct {
+	Serial uint64
+	Time   time.Time
+}
+
+// WriteDump writes every schema and object listed by l to w as a single file
+// of objects separated by blank lines, in the style of IRR database dumps.
+// The header is written as comment lines before the first object. When
+// compress is set the dump is gzip compressed.
+func WriteDump(w io.Writer, l Lister, hdr DumpHeader, compress bool) error {
+	if compress {
+		zw := gzip.NewWriter(w)
+		if err := writeDump(zw, l, hdr); err != nil {
+			return err
+		}
+		return zw.Close()
+	}
+
+	return writeDump(w, l, hdr)
+}
+
+func writeDump(w io.Writer, l Lister, hdr DumpHeader) error {
+	bw := bufio.NewWriter(w)
+
+	fmt.Fprintf(bw, "%% serial: %d\n", hdr.Serial)
+	if !hdr.Time.IsZero() {
+		fmt.Fprintf(bw, "%% timestamp: %s\n", hdr.Time.UTC().Format(time.RFC3339))
+	}
+
+	lis, err := l.ListObjects("schema")
+	if err != nil {
+		return err
+	}
+	schemas, err := ParseSchemas(lis)
+	if err != nil {
+		return err
+	}
+
+	write := func(lis ListObject) {
+		for _, dom := range lis {
+			bw.WriteString("\n")
+			bw.WriteString(dom.String())
+			bw.WriteString("\n")
+		}
+	}
+
+	write(lis)
+	for _, schema := range schemas.Items() {
+		if schema.Name == "schema" {
+			continue
+		}
+		lis, err := l.ListObjects(schema.Name)
+		if err != nil {
+			return err
+		}
+		write(lis)
+	}
+
+	return bw.Flush()
+}
+
+// ReadDump reads a dump written by WriteDump into memory. Gzip compressed
+// dumps are detected and decompressed.
+func ReadDump(r io.Reader) (*DumpHeader, *Memory, error) {
+	br := bufio.NewReader(r)
+	if magic, _ := br.Peek(2); bytes.Equal(magic, []byte{0x1f, 0x8b}) {
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, err
+		}
+		defer zr.Close()
+		br = bufio.NewReader(zr)
+	}
+
+	hdr, err := readDumpHeader(br)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	s, err := newSnapshot(ParseAll(br))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return hdr, newMemory(s), nil
+}
+
+// readDumpHeader reads the comment lines before the first object.
+func readDumpHeader(br *bufio.Reader) (*DumpHeader, error) {
+	hdr := &DumpHeader{}
+	for {
+		b, err := br.Peek(1)
+		if err == io.EOF {
+			return hdr, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b[0] != '%' && b[0] != '#' {
+			return hdr, nil
+		}
+
+		line, err := br.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+
+		sp := strings.SplitN(strings.TrimSpace(line[1:]), ":", 2)
+		if len(sp) != 2 {
+			continue
+		}
+		value := strings.TrimSpace(sp[1])
+
+		switch strings.TrimSpace(sp[0]) {
+		case "serial":
+			if hdr.Serial, err = strconv.ParseUint(value, 10, 64); err != nil {
+				return nil, fmt.Errorf("dump header: invalid serial %q", value)
+			}
+		case "timestamp":
+			if hdr.Time, err = time.Parse(time.RFC3339, value); err != nil {
+				return nil, fmt.Errorf("dump header: invalid timestamp %q", value)
+			}
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package rpsl_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestDump(t *testing.T) {
+	is := is.New(t)
+
+	r := rpsl.NewRPSL(rpsl.WithRPSLDir("testdata/registry"))
+	hdr := rpsl.DumpHeader{Serial: 42, Time: time.Date(2021, 3, 4, 5, 6, 7, 0, time.UTC)}
+
+	var buf bytes.Buffer
+	is.NoErr(rpsl.WriteDump(&buf, r, hdr, false))
+
+	txt := buf.String()
+	is.True(strings.HasPrefix(txt, "% serial: 42\n% timestamp: 2021-03-04T05:06:07Z\n\nschema:"))
+	is.True(strings.Contains(txt, "\n\ninetnum:            172.21.64.0 - 172.21.64.7\n"))
+
+	for _, compress := range []bool{false, true} {
+		buf.Reset()
+		is.NoErr(rpsl.WriteDump(&buf, r, hdr, compress))
+		is.Equal(bytes.HasPrefix(buf.Bytes(), []byte{0x1f, 0x8b}), compress)
+
+		got, m, err := rpsl.ReadDump(&buf)
+		is.NoErr(err)
+		is.Equal(*got, hdr)
+
+		dom, err := m.LoadObject("inetnum", "172.21.64.0/29")
+		is.NoErr(err)
+		is.Equal(dom.Get("mnt-by").Text(), "XUU-MNT")
+
+		lis, err := m.FindObject("XUU-MNT")
+		is.NoErr(err)
+		is.Equal(len(lis), 1)
+		is.Equal(lis[0].Schema(), "mntner")
+
+		lis, err = m.ListObjects("schema")
+		is.NoErr(err)
+		is.Equal(len(lis), 3)
+	}
+}
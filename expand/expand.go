@@ -0,0 +1,361 @@
+// Package expand flattens as-set and route-set objects into the ASNs and
+// prefixes they contain.
+package expand
+
+import (
+	"bytes"
+	"net"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+// Source of registry objects used for expansion.
+type Source interface {
+	rpsl.Fetcher
+	rpsl.Lister
+}
+
+// Result of expanding a set.
+type Result struct {
+	// Members are the sorted and unique ASNs or prefixes of the set.
+	Members []string
+
+	// Unresolved are set names that could not be loaded.
+	Unresolved []string
+
+	// Cycles are paths of set names that refer back to themselves.
+	Cycles [][]string
+}
+
+// Expander resolves set members through a Source.
+type Expander struct {
+	src    Source
+	routes *RouteIndex
+}
+
+// New creates an Expander reading from src.
+func New(src Source) *Expander {
+	return &Expander{src: src}
+}
+
+// Routes returns the route index used to resolve ASNs inside route-sets.
+// It is built from the source on first use.
+func (e *Expander) Routes() (*RouteIndex, error) {
+	if e.routes != nil {
+		return e.routes, nil
+	}
+
+	routes, err := NewRouteIndex(e.src)
+	if err != nil {
+		return nil, err
+	}
+	e.routes = routes
+
+	return routes, nil
+}
+
+// ASSet expands an as-set or ASN into the ASNs it contains.
+func (e *Expander) ASSet(name string) (*Result, error) {
+	w := &walker{Expander: e, schema: "as-set", members: rpsl.NewSet(), unresolved: rpsl.NewSet(), done: rpsl.NewSet()}
+	if err := w.walk(name, nil, ""); err != nil {
+		return nil, err
+	}
+
+	return w.result(sortASN), nil
+}
+
+// RouteSet expands a route-set, as-set or ASN into the prefixes it contains.
+// Range operators on members such as ^+ or ^24 are kept on the prefix.
+// An operator on a set member is applied to the prefixes of that set that
+// have none of their own.
+func (e *Expander) RouteSet(name string) (*Result, error) {
+	w := &walker{Expander: e, schema: "route-set", members: rpsl.NewSet(), unresolved: rpsl.NewSet(), done: rpsl.NewSet()}
+	if err := w.walk(name, nil, ""); err != nil {
+		return nil, err
+	}
+
+	return w.result(sortPrefix), nil
+}
+
+type walker struct {
+	*Expander
+
+	schema     string
+	members    *rpsl.Set
+	unresolved *rpsl.Set
+	done       *rpsl.Set
+	cycles     [][]string
+}
+
+func (w *walker) result(sorter func([]string)) *Result {
+	r := &Result{
+		Members:    w.members.Members(),
+		Unresolved: w.unresolved.Members(),
+		Cycles:     w.cycles,
+	}
+	sorter(r.Members)
+
+	return r
+}
+
+func (w *walker) walk(name string, stack []string, op string) error {
+	name = strings.ToUpper(name)
+
+	switch {
+	case IsASN(name):
+		if w.schema == "as-set" {
+			w.members.Add(name)
+			return nil
+		}
+
+		routes, err := w.Routes()
+		if err != nil {
+			return err
+		}
+		for _, prefix := range routes.Origin(name) {
+			w.members.Add(prefix + op)
+		}
+		return nil
+
+	case w.schema == "route-set" && isPrefix(name):
+		if !strings.ContainsRune(name, '^') {
+			name += op
+		}
+		w.members.Add(strings.ToLower(name))
+		return nil
+	}
+
+	for i, s := range stack {
+		if s == name {
+			w.cycles = append(w.cycles, append(append([]string{}, stack[i:]...), name))
+			return nil
+		}
+	}
+
+	key := name + op
+	if w.done.Has(key) {
+		return nil
+	}
+	w.done.Add(key)
+
+	schema := w.schema
+	if strings.HasPrefix(name, "AS-") || strings.Contains(name, ":AS-") {
+		schema = "as-set"
+	}
+
+	dom, err := w.src.LoadObject(schema, name)
+	if err == rpsl.NotFound {
+		w.unresolved.Add(name)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	stack = append(stack, name)
+	for _, member := range Members(dom) {
+		setOp := op
+		if i := strings.IndexRune(member, '^'); i > 0 && !isPrefix(member) {
+			member, setOp = member[:i], member[i:]
+		}
+
+		if err := w.walk(member, stack, setOp); err != nil {
+			return err
+		}
+	}
+
+	return w.walkRefs(dom, op)
+}
+
+// walkRefs adds objects that declare membership with member-of when the set
+// lists one of their maintainers in mbrs-by-ref.
+func (w *walker) walkRefs(dom *rpsl.Object, op string) error {
+	lis := splitMembers(dom.GetAll("mbrs-by-ref").Fields())
+	if len(lis) == 0 {
+		return nil
+	}
+
+	refs := rpsl.NewSet()
+	for _, s := range lis {
+		refs.Add(strings.ToUpper(s))
+	}
+
+	schemas := []string{"aut-num"}
+	if dom.Schema() == "route-set" {
+		schemas = []string{"route", "route6"}
+	}
+
+	for _, schema := range schemas {
+		lis, err := w.src.ListObjects(schema)
+		if err != nil {
+			return err
+		}
+
+		for _, ref := range lis {
+			if !hasField(ref.GetAll("member-of"), dom.Name()) {
+				continue
+			}
+			if !refs.Has("ANY") && !hasAny(ref.GetAll("mnt-by"), refs) {
+				continue
+			}
+
+			if schema == "aut-num" {
+				if err := w.walk(ref.Name(), nil, op); err != nil {
+					return err
+				}
+				continue
+			}
+			w.members.Add(strings.ToLower(ref.Name()) + op)
+		}
+	}
+
+	return nil
+}
+
+// Members returns the listed members and mp-members of a set object.
+func Members(dom *rpsl.Object) []string {
+	lis := append(dom.GetAll("members").Fields(), dom.GetAll("mp-members").Fields()...)
+	return splitMembers(lis)
+}
+
+func splitMembers(fields []string) []string {
+	var lis []string
+	for _, f := range fields {
+		for _, s := range strings.Split(f, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				lis = append(lis, s)
+			}
+		}
+	}
+	return lis
+}
+
+func hasField(lis rpsl.ListAttribute, name string) bool {
+	for _, s := range splitMembers(lis.Fields()) {
+		if strings.EqualFold(s, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAny(lis rpsl.ListAttribute, set *rpsl.Set) bool {
+	for _, s := range splitMembers(lis.Fields()) {
+		if set.Has(strings.ToUpper(s)) {
+			return true
+		}
+	}
+	return false
+}
+
+var asnRE = regexp.MustCompile(`^AS[0-9]+$`)
+
+// IsASN reports whether s is an AS number such as AS4242420000.
+func IsASN(s string) bool {
+	return asnRE.MatchString(strings.ToUpper(s))
+}
+
+func isPrefix(s string) bool {
+	if i := strings.IndexRune(s, '^'); i > 0 {
+		s = s[:i]
+	}
+	_, _, err := net.ParseCIDR(s)
+	return err == nil
+}
+
+func sortASN(lis []string) {
+	sort.Slice(lis, func(i, j int) bool {
+		a, _ := strconv.ParseUint(lis[i][2:], 10, 32)
+		b, _ := strconv.ParseUint(lis[j][2:], 10, 32)
+		return a < b
+	})
+}
+
+func sortPrefix(lis []string) {
+	sort.Slice(lis, func(i, j int) bool {
+		return comparePrefix(lis[i], lis[j]) < 0
+	})
+}
+
+// comparePrefix orders IPv4 before IPv6, then by address and length.
+func comparePrefix(a, b string) int {
+	an, ao := parsePrefix(a)
+	bn, bo := parsePrefix(b)
+	if an == nil || bn == nil {
+		return strings.Compare(a, b)
+	}
+
+	a4, b4 := an.IP.To4() != nil, bn.IP.To4() != nil
+	if a4 != b4 {
+		if a4 {
+			return -1
+		}
+		return 1
+	}
+	if c := bytes.Compare(an.IP.To16(), bn.IP.To16()); c != 0 {
+		return c
+	}
+	al, _ := an.Mask.Size()
+	bl, _ := bn.Mask.Size()
+	if al != bl {
+		if al < bl {
+			return -1
+		}
+		return 1
+	}
+	return strings.Compare(ao, bo)
+}
+
+func parsePrefix(s string) (*net.IPNet, string) {
+	op := ""
+	if i := strings.IndexRune(s, '^'); i > 0 {
+		s, op = s[:i], s[i:]
+	}
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, op
+	}
+	return n, op
+}
+
+// RouteIndex maps origin ASNs to the prefixes of their route objects.
+type RouteIndex struct {
+	origins map[string][]string
+}
+
+// NewRouteIndex builds an index of route and route6 objects in src.
+func NewRouteIndex(src rpsl.Lister) (*RouteIndex, error) {
+	idx := &RouteIndex{origins: make(map[string][]string)}
+
+	for _, schema := range []string{"route", "route6"} {
+		lis, err := src.ListObjects(schema)
+		if err != nil {
+			return nil, err
+		}
+		for _, dom := range lis {
+			idx.Add(dom)
+		}
+	}
+
+	return idx, nil
+}
+
+// Add a route object to the index.
+func (idx *RouteIndex) Add(dom *rpsl.Object) {
+	prefix := strings.ToLower(dom.Get(dom.Schema()).Text())
+	for _, origin := range dom.GetAll("origin").Fields() {
+		origin = strings.ToUpper(origin)
+		idx.origins[origin] = append(idx.origins[origin], prefix)
+	}
+}
+
+// Origin returns the sorted prefixes originated by asn.
+func (idx *RouteIndex) Origin(asn string) []string {
+	lis := append([]string(nil), idx.origins[strings.ToUpper(asn)]...)
+	sortPrefix(lis)
+	return lis
+}
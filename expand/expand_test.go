@@ -0,0 +1,106 @@
+package expand_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/expand"
+)
+
+const txtSets = `as-set:             AS-OUTER
+members:            AS4242420001, AS-INNER
+members:            AS-MISSING
+mbrs-by-ref:        XUU-MNT
+
+as-set:             AS-INNER
+members:            AS4242420002 AS-OUTER
+members:            AS4242420001
+
+aut-num:            AS4242420003
+member-of:          AS-OUTER
+mnt-by:             XUU-MNT
+
+aut-num:            AS4242420004
+member-of:          AS-OUTER
+mnt-by:             OTHER-MNT
+
+route-set:          RS-OUTER
+mp-members:         fd42:4242:2601::/48^+
+members:            172.20.0.0/24, RS-INNER^25
+members:            AS-INNER
+mbrs-by-ref:        ANY
+
+route-set:          RS-INNER
+members:            172.20.1.0/24 172.20.2.0/24^26
+
+route:              172.21.0.0/24
+origin:             AS4242420002
+
+route:              172.22.0.0/24
+origin:             AS4242420005
+member-of:          RS-OUTER
+mnt-by:             OTHER-MNT
+`
+
+type memory map[string]rpsl.ListObject
+
+func (m memory) LoadObject(schema, name string) (*rpsl.Object, error) {
+	for _, dom := range m[schema] {
+		if dom.Name() == name {
+			return dom, nil
+		}
+	}
+	return nil, rpsl.NotFound
+}
+func (m memory) ListObjects(schema string) (rpsl.ListObject, error) {
+	return m[schema], nil
+}
+
+func newMemory(s string) memory {
+	m := make(memory)
+	for _, dom := range rpsl.ParseAll(strings.NewReader(s)) {
+		m[dom.Schema()] = append(m[dom.Schema()], dom)
+	}
+	return m
+}
+
+func TestASSet(t *testing.T) {
+	is := is.New(t)
+
+	e := expand.New(newMemory(txtSets))
+
+	r, err := e.ASSet("AS-OUTER")
+	is.NoErr(err)
+	is.Equal(r.Members, []string{"AS4242420001", "AS4242420002", "AS4242420003"})
+	is.Equal(r.Unresolved, []string{"AS-MISSING"})
+	is.Equal(r.Cycles, [][]string{{"AS-OUTER", "AS-INNER", "AS-OUTER"}})
+
+	r, err = e.ASSet("AS4242420009")
+	is.NoErr(err)
+	is.Equal(r.Members, []string{"AS4242420009"})
+}
+
+func TestRouteSet(t *testing.T) {
+	is := is.New(t)
+
+	e := expand.New(newMemory(txtSets))
+
+	r, err := e.RouteSet("RS-OUTER")
+	is.NoErr(err)
+	is.Equal(r.Members, []string{
+		"172.20.0.0/24",
+		"172.20.1.0/24^25",
+		"172.20.2.0/24^26",
+		"172.21.0.0/24",
+		"172.22.0.0/24",
+		"fd42:4242:2601::/48^+",
+	})
+	is.Equal(r.Unresolved, []string{"AS-MISSING"})
+	is.Equal(len(r.Cycles), 1)
+
+	idx, err := e.Routes()
+	is.NoErr(err)
+	is.Equal(idx.Origin("as4242420002"), []string{"172.21.0.0/24"})
+}
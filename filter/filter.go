@@ -0,0 +1,348 @@
+// Package filter generates BGP prefix filters from aut-num, as-set and
+// route-set objects in the style of bgpq4.
+package filter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	"rpsl.dn42.us/go-rpsl/expand"
+)
+
+// Entry permits a prefix and its more specifics from Min to Max length.
+type Entry struct {
+	Prefix *net.IPNet
+	Min    int
+	Max    int
+}
+
+// Len returns the prefix length of the entry.
+func (e Entry) Len() int {
+	l, _ := e.Prefix.Mask.Size()
+	return l
+}
+
+// Exact reports whether only the prefix itself is permitted.
+func (e Entry) Exact() bool {
+	return e.Min == e.Len() && e.Max == e.Len()
+}
+
+func (e Entry) String() string {
+	if e.Exact() {
+		return e.Prefix.String()
+	}
+	return fmt.Sprintf("%s{%d,%d}", e.Prefix, e.Min, e.Max)
+}
+
+func (e Entry) bits() int {
+	_, bits := e.Prefix.Mask.Size()
+	return bits
+}
+
+// covers reports whether e permits every prefix that o permits.
+func (e Entry) covers(o Entry) bool {
+	return e.bits() == o.bits() &&
+		e.Len() <= o.Len() &&
+		e.Prefix.Contains(o.Prefix.IP) &&
+		e.Min <= o.Min && e.Max >= o.Max
+}
+
+// Options for generating a filter.
+type Options struct {
+	// Name of the generated list. Defaults to the target name.
+	Name string
+
+	// IPv6 selects IPv6 prefixes instead of IPv4.
+	IPv6 bool
+
+	// Aggregate merges adjacent and covered prefixes.
+	Aggregate bool
+
+	// MaxLength permits more specifics up to this length.
+	MaxLength int
+}
+
+// List is a generated prefix filter.
+type List struct {
+	Name    string
+	IPv6    bool
+	Entries []Entry
+
+	// Unresolved set names found while expanding.
+	Unresolved []string
+}
+
+// Generate a prefix filter for an ASN, as-set or route-set.
+func Generate(e *expand.Expander, target string, opts Options) (*List, error) {
+	r, err := e.RouteSet(target)
+	if err != nil {
+		return nil, err
+	}
+
+	lis := &List{Name: opts.Name, IPv6: opts.IPv6, Unresolved: r.Unresolved}
+	if lis.Name == "" {
+		lis.Name = target
+	}
+
+	for _, member := range r.Members {
+		entry, err := ParseEntry(member)
+		if err != nil {
+			return nil, err
+		}
+		if (entry.Prefix.IP.To4() == nil) != opts.IPv6 {
+			continue
+		}
+		if opts.MaxLength > entry.Max {
+			entry.Max = opts.MaxLength
+			if entry.Max > entry.bits() {
+				entry.Max = entry.bits()
+			}
+		}
+		lis.Entries = append(lis.Entries, entry)
+	}
+
+	sortEntries(lis.Entries)
+	if opts.Aggregate {
+		lis.Entries = Aggregate(lis.Entries)
+	}
+
+	return lis, nil
+}
+
+// ParseEntry parses a prefix with an optional RPSL range operator such as
+// ^-, ^+, ^n or ^n-m.
+func ParseEntry(s string) (Entry, error) {
+	op := ""
+	if i := strings.IndexRune(s, '^'); i > 0 {
+		s, op = s[:i], s[i+1:]
+	}
+
+	_, prefix, err := net.ParseCIDR(s)
+	if err != nil {
+		return Entry{}, err
+	}
+
+	e := Entry{Prefix: prefix}
+	l, bits := prefix.Mask.Size()
+
+	switch op {
+	case "":
+		e.Min, e.Max = l, l
+	case "-":
+		e.Min, e.Max = l+1, bits
+	case "+":
+		e.Min, e.Max = l, bits
+	default:
+		sp := strings.SplitN(op, "-", 2)
+		if e.Min, err = strconv.Atoi(sp[0]); err != nil {
+			return Entry{}, fmt.Errorf("invalid range operator ^%s", op)
+		}
+		e.Max = e.Min
+		if len(sp) == 2 {
+			if e.Max, err = strconv.Atoi(sp[1]); err != nil {
+				return Entry{}, fmt.Errorf("invalid range operator ^%s", op)
+			}
+		}
+	}
+
+	if e.Min < l || e.Max > bits || e.Min > e.Max {
+		return Entry{}, fmt.Errorf("range operator ^%s out of range for %s", op, prefix)
+	}
+
+	return e, nil
+}
+
+// Aggregate removes entries covered by another entry and merges sibling
+// prefixes with the same length range into their parent.
+func Aggregate(lis []Entry) []Entry {
+	lis = append([]Entry(nil), lis...)
+
+	for {
+		sortEntries(lis)
+		out := removeCovered(lis)
+		out, merged := mergeSiblings(out)
+		lis = out
+		if !merged {
+			return lis
+		}
+	}
+}
+
+func removeCovered(lis []Entry) []Entry {
+	out := make([]Entry, 0, len(lis))
+	for i, e := range lis {
+		covered := false
+		for j, o := range lis {
+			if i != j && o.covers(e) && (!e.covers(o) || j < i) {
+				covered = true
+				break
+			}
+		}
+		if !covered {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func mergeSiblings(lis []Entry) ([]Entry, bool) {
+	out := make([]Entry, 0, len(lis))
+	merged := false
+	for i := 0; i < len(lis); i++ {
+		e := lis[i]
+		if i+1 < len(lis) && e.Len() > 0 {
+			o := lis[i+1]
+			parent := &net.IPNet{IP: e.Prefix.IP, Mask: net.CIDRMask(e.Len()-1, e.bits())}
+			if o.Len() == e.Len() && o.Min == e.Min && o.Max == e.Max &&
+				parent.IP.Equal(e.Prefix.IP.Mask(parent.Mask)) &&
+				parent.Contains(o.Prefix.IP) && !e.Prefix.IP.Equal(o.Prefix.IP) {
+				out = append(out, Entry{Prefix: parent, Min: e.Min, Max: e.Max})
+				merged = true
+				i++
+				continue
+			}
+		}
+		out = append(out, e)
+	}
+	return out, merged
+}
+
+func sortEntries(lis []Entry) {
+	sort.Slice(lis, func(i, j int) bool {
+		a, b := lis[i], lis[j]
+		if c := bytes.Compare(a.Prefix.IP.To16(), b.Prefix.IP.To16()); c != 0 {
+			return c < 0
+		}
+		if a.Len() != b.Len() {
+			return a.Len() < b.Len()
+		}
+		if a.Min != b.Min {
+			return a.Min < b.Min
+		}
+		return a.Max < b.Max
+	})
+}
+
+// WriteBird writes the list as a bird2 prefix set definition. Bird has no
+// empty prefix set so an empty list is an error.
+func (lis *List) WriteBird(w io.Writer) error {
+	if len(lis.Entries) == 0 {
+		return fmt.Errorf("filter: %s: empty prefix set can't be written for bird", lis.Name)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "define %s = [", identifier(lis.Name))
+	for i, e := range lis.Entries {
+		if i > 0 {
+			b.WriteRune(',')
+		}
+		b.WriteString("\n    ")
+		b.WriteString(e.String())
+	}
+	b.WriteString("\n];\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteCisco writes the list as a Cisco IOS prefix-list.
+func (lis *List) WriteCisco(w io.Writer) error {
+	kind, all := "ip", "0.0.0.0/0"
+	if lis.IPv6 {
+		kind, all = "ipv6", "::/0"
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "no %s prefix-list %s\n", kind, lis.Name)
+	if len(lis.Entries) == 0 {
+		fmt.Fprintf(&b, "%s prefix-list %s deny %s\n", kind, lis.Name, all)
+	}
+	for _, e := range lis.Entries {
+		fmt.Fprintf(&b, "%s prefix-list %s permit %s", kind, lis.Name, e.Prefix)
+		if e.Min > e.Len() {
+			fmt.Fprintf(&b, " ge %d", e.Min)
+		}
+		if e.Max > e.Len() {
+			fmt.Fprintf(&b, " le %d", e.Max)
+		}
+		b.WriteRune('\n')
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// WriteJuniper writes the list as a Juniper policy-statement. An empty list
+// has no prefixes term, as a term without conditions matches every route, so
+// all routes are rejected.
+func (lis *List) WriteJuniper(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("policy-options {\nreplace:\n")
+	fmt.Fprintf(&b, "    policy-statement %s {\n", lis.Name)
+	if len(lis.Entries) > 0 {
+		b.WriteString("        term prefixes {\n")
+		b.WriteString("            from {\n")
+		for _, e := range lis.Entries {
+			fmt.Fprintf(&b, "                route-filter %s ", e.Prefix)
+			switch {
+			case e.Exact():
+				b.WriteString("exact")
+			case e.Min == e.Len():
+				fmt.Fprintf(&b, "upto /%d", e.Max)
+			default:
+				fmt.Fprintf(&b, "prefix-length-range /%d-/%d", e.Min, e.Max)
+			}
+			b.WriteString(";\n")
+		}
+		b.WriteString("            }\n")
+		b.WriteString("            then next policy;\n")
+		b.WriteString("        }\n")
+	}
+	b.WriteString("        then reject;\n")
+	b.WriteString("    }\n")
+	b.WriteString("}\n")
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+type jsonEntry struct {
+	Prefix       string `json:"prefix"`
+	Exact        bool   `json:"exact"`
+	GreaterEqual int    `json:"greater-equal,omitempty"`
+	LessEqual    int    `json:"less-equal,omitempty"`
+}
+
+// WriteJSON writes the list as a JSON object keyed by name.
+func (lis *List) WriteJSON(w io.Writer) error {
+	entries := make([]jsonEntry, len(lis.Entries))
+	for i, e := range lis.Entries {
+		entries[i] = jsonEntry{Prefix: e.Prefix.String(), Exact: e.Exact()}
+		if !e.Exact() {
+			entries[i].GreaterEqual = e.Min
+			entries[i].LessEqual = e.Max
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string][]jsonEntry{lis.Name: entries})
+}
+
+// identifier replaces characters not allowed in bird symbol names.
+func identifier(s string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '_':
+			return r
+		default:
+			return '_'
+		}
+	}, s)
+}
@@ -0,0 +1,153 @@
+package filter_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/expand"
+	"rpsl.dn42.us/go-rpsl/filter"
+)
+
+const txtObjects = `as-set:             AS-FOO
+members:            AS4242420001 AS4242420002
+
+route:              172.20.0.0/25
+origin:             AS4242420001
+
+route:              172.20.0.128/25
+origin:             AS4242420002
+
+route:              172.20.0.0/26
+origin:             AS4242420002
+
+route:              172.21.0.0/24
+origin:             AS4242420002
+max-length:         28
+
+route6:             fd42:4242:2601::/48
+origin:             AS4242420001
+`
+
+type memory map[string]rpsl.ListObject
+
+func (m memory) LoadObject(schema, name string) (*rpsl.Object, error) {
+	for _, dom := range m[schema] {
+		if dom.Name() == name {
+			return dom, nil
+		}
+	}
+	return nil, rpsl.NotFound
+}
+func (m memory) ListObjects(schema string) (rpsl.ListObject, error) {
+	return m[schema], nil
+}
+
+func newExpander() *expand.Expander {
+	m := make(memory)
+	for _, dom := range rpsl.ParseAll(strings.NewReader(txtObjects)) {
+		m[dom.Schema()] = append(m[dom.Schema()], dom)
+	}
+	return expand.New(m)
+}
+
+func TestGenerate(t *testing.T) {
+	is := is.New(t)
+
+	e := newExpander()
+
+	lis, err := filter.Generate(e, "AS-FOO", filter.Options{Name: "AS_FOO"})
+	is.NoErr(err)
+	is.Equal(len(lis.Entries), 4)
+
+	var b strings.Builder
+	is.NoErr(lis.WriteBird(&b))
+	is.Equal(b.String(), `define AS_FOO = [
+    172.20.0.0/25,
+    172.20.0.0/26,
+    172.20.0.128/25,
+    172.21.0.0/24
+];
+`)
+
+	lis, err = filter.Generate(e, "AS-FOO", filter.Options{Aggregate: true, MaxLength: 26})
+	is.NoErr(err)
+	is.Equal(lis.Name, "AS-FOO")
+
+	b.Reset()
+	is.NoErr(lis.WriteCisco(&b))
+	is.Equal(b.String(), `no ip prefix-list AS-FOO
+ip prefix-list AS-FOO permit 172.20.0.0/24 ge 25 le 26
+ip prefix-list AS-FOO permit 172.21.0.0/24 le 26
+`)
+
+	b.Reset()
+	is.NoErr(lis.WriteJuniper(&b))
+	is.True(strings.Contains(b.String(), "route-filter 172.20.0.0/24 prefix-length-range /25-/26;"))
+	is.True(strings.Contains(b.String(), "route-filter 172.21.0.0/24 upto /26;"))
+
+	lis, err = filter.Generate(e, "AS4242420001", filter.Options{IPv6: true})
+	is.NoErr(err)
+
+	b.Reset()
+	is.NoErr(lis.WriteJSON(&b))
+	is.Equal(b.String(), `{
+  "AS4242420001": [
+    {
+      "prefix": "fd42:4242:2601::/48",
+      "exact": true
+    }
+  ]
+}
+`)
+
+	lis, err = filter.Generate(e, "AS4242420009", filter.Options{IPv6: true})
+	is.NoErr(err)
+
+	b.Reset()
+	is.NoErr(lis.WriteCisco(&b))
+	is.Equal(b.String(), "no ipv6 prefix-list AS4242420009\nipv6 prefix-list AS4242420009 deny ::/0\n")
+
+	// Empty lists reject every route.
+	b.Reset()
+	is.NoErr(lis.WriteJuniper(&b))
+	is.Equal(b.String(), `policy-options {
+replace:
+    policy-statement AS4242420009 {
+        then reject;
+    }
+}
+`)
+
+	b.Reset()
+	err = lis.WriteBird(&b)
+	is.True(err != nil)
+	is.Equal(b.Len(), 0)
+}
+
+func TestParseEntry(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		in       string
+		min, max int
+	}{
+		{"172.20.0.0/24", 24, 24},
+		{"172.20.0.0/24^-", 25, 32},
+		{"172.20.0.0/24^+", 24, 32},
+		{"172.20.0.0/24^26", 26, 26},
+		{"172.20.0.0/24^25-28", 25, 28},
+	}
+	for _, tt := range tests {
+		e, err := filter.ParseEntry(tt.in)
+		is.NoErr(err)
+		is.Equal(e.Min, tt.min)
+		is.Equal(e.Max, tt.max)
+	}
+
+	for _, in := range []string{"172.20.0.0", "172.20.0.0/24^x", "172.20.0.0/24^16", "172.20.0.0/24^28-26"} {
+		_, err := filter.ParseEntry(in)
+		is.True(err != nil)
+	}
+}
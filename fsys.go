@@ -0,0 +1,61 @@
+package rpsl
+
+import (
+	"errors"
+	"io/fs"
+	"path"
+	"strings"
+)
+
+// WithFS reads objects from a registry data directory at the root of fsys,
+// such as an embed.FS, zip.Reader or os.DirFS. Use fs.Sub when the data
+// directory is nested within fsys.
+func WithFS(fsys fs.FS) Option {
+	return WithFetcher(&registry{src: fsSource{fsys}})
+}
+
+type fsSource struct {
+	fsys fs.FS
+}
+
+func (s fsSource) readFile(schema, file string) ([]byte, error) {
+	if !validFile(schema) || !validFile(file) {
+		return nil, NotFound
+	}
+
+	b, err := fs.ReadFile(s.fsys, path.Join(schema, file))
+	if errors.Is(err, fs.ErrNotExist) || errors.Is(err, fs.ErrInvalid) {
+		return nil, NotFound
+	}
+	return b, err
+}
+
+func (s fsSource) readDir(schema string) ([]string, error) {
+	if !validFile(schema) {
+		return nil, nil
+	}
+
+	entries, err := fs.ReadDir(s.fsys, schema)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+		names = append(names, entry.Name())
+	}
+
+	return names, nil
+}
+
+// validFile reports whether s names a file directly within a registry
+// directory, so that it can't be used to read or write elsewhere.
+func validFile(s string) bool {
+	return fs.ValidPath(s) && s != "." && !strings.ContainsAny(s, "/\\\n")
+}
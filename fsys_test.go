@@ -0,0 +1,75 @@
+package rpsl_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"embed"
+	"io/fs"
+	"os"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+//go:embed testdata/registry
+var testRegistry embed.FS
+
+func TestWithFS(t *testing.T) {
+	is := is.New(t)
+
+	sub, err := fs.Sub(testRegistry, "testdata/registry")
+	is.NoErr(err)
+
+	// The same registry as a zip archive.
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	is.NoErr(fs.WalkDir(sub, ".", func(name string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		b, err := fs.ReadFile(sub, name)
+		if err != nil {
+			return err
+		}
+		f, err := w.Create("registry/" + name)
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(b)
+		return err
+	}))
+	is.NoErr(w.Close())
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	is.NoErr(err)
+	zsub, err := fs.Sub(zr, "registry")
+	is.NoErr(err)
+
+	for name, fsys := range map[string]fs.FS{"embed": sub, "zip": zsub, "dir": os.DirFS("testdata/registry")} {
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+			r := rpsl.NewRPSL(rpsl.WithFS(fsys))
+
+			dom, err := r.LoadObject("inetnum", "172.21.64.0/29")
+			is.NoErr(err)
+			is.Equal(dom.Name(), "172.21.64.0/29")
+			is.Equal(dom.Get("mnt-by").Args().String(), "lookup:mntner/XUU-MNT")
+
+			lis, err := r.ListObjects("person")
+			is.NoErr(err)
+			is.Equal(len(lis), 1)
+			is.Equal(lis[0].Name(), "XUU-DN42")
+
+			found, err := r.FindObject("XUU-MNT")
+			is.NoErr(err)
+			is.Equal(len(found), 1)
+
+			_, err = r.LoadObject("person", "../mntner/XUU-MNT")
+			is.Equal(err, rpsl.NotFound)
+
+			lis, err = r.ListObjects("route")
+			is.NoErr(err)
+			is.Equal(len(lis), 0)
+		})
+	}
+}
@@ -0,0 +1,156 @@
+package rpsl
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// GitFS reads a registry from the data directory of a git repository at a
+// fixed commit without a working tree. Objects are read through a long running
+// git cat-file process that is stopped by Close.
+type GitFS struct {
+	*registry
+
+	// Commit the revision resolved to.
+	Commit string
+
+	mu  sync.Mutex
+	cmd *exec.Cmd
+	in  io.WriteCloser
+	out *bufio.Reader
+}
+
+// NewGitFS reads objects under data/ of the git repository at repo as of the
+// revision rev. Rev may be any revision git understands such as a branch,
+// tag or commit hash.
+func NewGitFS(repo, rev string) (*GitFS, error) {
+	b, err := exec.Command("git", "-C", repo, "rev-parse", "--verify", "--quiet", rev+"^{commit}").Output()
+	if err != nil {
+		return nil, fmt.Errorf("git: unknown revision %s", rev)
+	}
+
+	g := &GitFS{Commit: strings.TrimSpace(string(b))}
+	g.registry = &registry{src: g}
+
+	g.cmd = exec.Command("git", "-C", repo, "cat-file", "--batch")
+	if g.in, err = g.cmd.StdinPipe(); err != nil {
+		return nil, err
+	}
+	out, err := g.cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	g.out = bufio.NewReader(out)
+
+	if err := g.cmd.Start(); err != nil {
+		return nil, fmt.Errorf("git: %w", err)
+	}
+
+	return g, nil
+}
+
+// Close stops the git process.
+func (g *GitFS) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.in.Close()
+	return g.cmd.Wait()
+}
+
+// object reads the git object at path in the commit. NotFound is returned
+// when it does not exist.
+func (g *GitFS) object(name string) (typ string, content []byte, err error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	if _, err := fmt.Fprintf(g.in, "%s:%s\n", g.Commit, name); err != nil {
+		return "", nil, fmt.Errorf("git: %w", err)
+	}
+
+	header, err := g.out.ReadString('\n')
+	if err != nil {
+		return "", nil, fmt.Errorf("git: %w", err)
+	}
+
+	fields := strings.Fields(header)
+	if len(fields) == 2 && fields[1] == "missing" {
+		return "", nil, NotFound
+	}
+	if len(fields) != 3 {
+		return "", nil, fmt.Errorf("git: unexpected response %q", header)
+	}
+
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return "", nil, fmt.Errorf("git: unexpected response %q", header)
+	}
+
+	content = make([]byte, size+1)
+	if _, err := io.ReadFull(g.out, content); err != nil {
+		return "", nil, fmt.Errorf("git: %w", err)
+	}
+
+	return fields[1], content[:size], nil
+}
+
+func (g *GitFS) readFile(schema, file string) ([]byte, error) {
+	if !validFile(schema) || !validFile(file) {
+		return nil, NotFound
+	}
+
+	typ, b, err := g.object(path.Join("data", schema, file))
+	if err != nil {
+		return nil, err
+	}
+	if typ != "blob" {
+		return nil, NotFound
+	}
+
+	return b, nil
+}
+
+func (g *GitFS) readDir(schema string) ([]string, error) {
+	if !validFile(schema) {
+		return nil, nil
+	}
+
+	typ, b, err := g.object(path.Join("data", schema))
+	if err == NotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if typ != "tree" {
+		return nil, nil
+	}
+
+	// Tree entries are "<mode> <name>\x00<hash>" with a binary hash the size
+	// of the commit hash. Only regular files are objects.
+	hashLen := len(g.Commit) / 2
+
+	var names []string
+	for len(b) > 0 {
+		i := bytes.IndexByte(b, 0)
+		if i < 0 || len(b) < i+1+hashLen {
+			return nil, fmt.Errorf("git: malformed tree data/%s", schema)
+		}
+		entry := strings.SplitN(string(b[:i]), " ", 2)
+		b = b[i+1+hashLen:]
+
+		if len(entry) != 2 || !strings.HasPrefix(entry[0], "100") || strings.HasPrefix(entry[1], ".") {
+			continue
+		}
+		names = append(names, entry[1])
+	}
+
+	return names, nil
+}
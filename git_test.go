@@ -0,0 +1,92 @@
+package rpsl_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestGitFS(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	is := is.New(t)
+
+	lis := rpsl.ParseAll(strings.NewReader(cleanDoc(txtAllObjects)))
+	repo := writeRegistry(t, rpsl.ListObject{})
+	is.NoErr(os.Rename(writeRegistry(t, lis), filepath.Join(repo, "data")))
+
+	git := func(args ...string) string {
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("git %v: %v\n%s", args, err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+
+	git("init", "-q")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+	first := git("rev-parse", "HEAD")
+
+	git("rm", "-q", "data/person/XUU-DN42")
+	git("commit", "-q", "-m", "remove person")
+
+	g, err := rpsl.NewGitFS(repo, first)
+	is.NoErr(err)
+	defer g.Close()
+	is.Equal(g.Commit, first)
+
+	r := rpsl.NewRPSL(rpsl.WithFetcher(g))
+	dom, err := r.LoadObject("inetnum", "172.21.64.0/29")
+	is.NoErr(err)
+	is.Equal(dom.Name(), "172.21.64.0/29")
+	is.Equal(dom.Get("mnt-by").Text(), "XUU-MNT")
+
+	dom, err = r.LoadObject("person", "XUU-DN42")
+	is.NoErr(err)
+	is.Equal(dom.Get("person").Text(), "Xuu")
+
+	objects, err := r.ListObjects("mntner")
+	is.NoErr(err)
+	is.Equal(len(objects), 2)
+
+	found, err := r.FindObject("XUU-MNT")
+	is.NoErr(err)
+	is.Equal(found[0].Schema(), "mntner")
+
+	_, err = r.LoadObject("mntner", "MISSING-MNT")
+	is.Equal(err, rpsl.NotFound)
+
+	// Names that would escape the data directory or break the batch
+	// protocol are not found, and later lookups still work.
+	_, err = r.LoadObject("mntner", "XUU-MNT\n"+first+":data/person/XUU-DN42")
+	is.Equal(err, rpsl.NotFound)
+	_, err = r.LoadObject("..", "README")
+	is.Equal(err, rpsl.NotFound)
+	objects, err = r.ListObjects("../data/mntner")
+	is.NoErr(err)
+	is.Equal(len(objects), 0)
+	dom, err = r.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(dom.Name(), "XUU-MNT")
+
+	head, err := rpsl.NewGitFS(repo, "HEAD")
+	is.NoErr(err)
+	defer head.Close()
+	_, err = head.LoadObject("person", "XUU-DN42")
+	is.Equal(err, rpsl.NotFound)
+
+	d, err := rpsl.DiffTree(g, head)
+	is.NoErr(err)
+	is.Equal(d.String(), "deleted person XUU-DN42\n")
+
+	_, err = rpsl.NewGitFS(repo, "no-such-branch")
+	is.True(err != nil)
+}
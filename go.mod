@@ -1,5 +1,5 @@
 module rpsl.dn42.us/go-rpsl
 
-go 1.14
+go 1.16
 
 require github.com/matryer/is v1.4.0
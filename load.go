@@ -0,0 +1,139 @@
+package rpsl
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// LoadError is a file that could not be loaded.
+type LoadError struct {
+	Path string
+	Err  error
+}
+
+func (e *LoadError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *LoadError) Unwrap() error { return e.Err }
+
+// LoadDir reads a registry data directory into memory. See LoadFS.
+func LoadDir(ctx context.Context, dir string, workers int) (*Memory, error) {
+	return LoadFS(ctx, os.DirFS(dir), workers)
+}
+
+// LoadFS reads every object in the registry data directory at the root of
+// fsys into memory, parsing files concurrently with a pool of workers. A
+// workers count below one uses GOMAXPROCS.
+//
+// Files that fail to load are skipped and reported together as a list of
+// *LoadError once the rest of the registry has loaded, so the returned memory
+// may be used along with a non-nil error. When ctx is cancelled loading stops
+// and only the context error is returned.
+func LoadFS(ctx context.Context, fsys fs.FS, workers int) (*Memory, error) {
+	if workers < 1 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	files, err := walkRegistry(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	var (
+		lis  = make(ListObject, len(files))
+		jobs = make(chan int)
+		wg   sync.WaitGroup
+
+		mu   sync.Mutex
+		errs errorList
+	)
+
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				b, err := fs.ReadFile(fsys, files[i])
+				if err != nil {
+					mu.Lock()
+					errs = append(errs, &LoadError{Path: files[i], Err: err})
+					mu.Unlock()
+					continue
+				}
+				lis[i] = ParseObject(string(b))
+			}
+		}()
+	}
+
+send:
+	for i := range files {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+			break send
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	loaded := lis[:0]
+	for _, dom := range lis {
+		if dom != nil {
+			loaded = append(loaded, dom)
+		}
+	}
+
+	s, err := newSnapshot(loaded)
+	if err != nil {
+		return nil, err
+	}
+	m := newMemory(s)
+
+	if len(errs) > 0 {
+		sort.Slice(errs, func(i, j int) bool {
+			return errs[i].(*LoadError).Path < errs[j].(*LoadError).Path
+		})
+		return m, errs
+	}
+
+	return m, nil
+}
+
+// walkRegistry lists the object files in each schema directory of fsys.
+func walkRegistry(fsys fs.FS) ([]string, error) {
+	dirs, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	src := fsSource{fsys}
+
+	var files []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || strings.HasPrefix(dir.Name(), ".") {
+			continue
+		}
+
+		names, err := src.readDir(dir.Name())
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			files = append(files, path.Join(dir.Name(), name))
+		}
+	}
+
+	return files, nil
+}
@@ -0,0 +1,112 @@
+package rpsl_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+// failFS fails to open the file named bad.
+type failFS struct {
+	fs.FS
+	bad string
+}
+
+var errFail = errors.New("read failed")
+
+func (f failFS) Open(name string) (fs.File, error) {
+	if name == f.bad {
+		return nil, errFail
+	}
+	return f.FS.Open(name)
+}
+
+func TestLoad(t *testing.T) {
+	is := is.New(t)
+
+	m, err := rpsl.LoadDir(context.Background(), "testdata/registry", 2)
+	is.NoErr(err)
+
+	dom, err := m.LoadObject("inetnum", "172.21.64.0/29")
+	is.NoErr(err)
+	is.Equal(dom.Primary(), "cidr")
+
+	lis, err := m.ListObjects("schema")
+	is.NoErr(err)
+	is.Equal(len(lis), 3)
+
+	fsys := failFS{os.DirFS("testdata/registry"), "person/XUU-DN42"}
+	m, err = rpsl.LoadFS(context.Background(), fsys, 0)
+	is.Equal(err.Error(), "person/XUU-DN42: read failed")
+
+	_, err = m.LoadObject("person", "XUU-DN42")
+	is.Equal(err, rpsl.NotFound)
+	_, err = m.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err = rpsl.LoadFS(ctx, fstest.MapFS{
+		"mntner/XUU-MNT": {Data: []byte("mntner: XUU-MNT\n")},
+	}, 1)
+	is.Equal(err, context.Canceled)
+}
+
+// BenchmarkLoad reads a generated registry roughly the size of the dn42
+// registry, one file at a time and with a pool of workers.
+func BenchmarkLoad(b *testing.B) {
+	dir := b.TempDir()
+	counts := map[string]int{
+		"aut-num":  4000,
+		"inetnum":  5000,
+		"inet6num": 3000,
+		"route":    4000,
+		"route6":   3000,
+		"person":   3000,
+		"mntner":   3000,
+		"domain":   1500,
+	}
+	for schema, n := range counts {
+		if err := os.MkdirAll(filepath.Join(dir, schema), 0755); err != nil {
+			b.Fatal(err)
+		}
+		for i := 0; i < n; i++ {
+			name := fmt.Sprintf("%s-%d", schema, i)
+			dom := rpsl.ParseObject(cleanDoc(fmt.Sprintf(`
+				%s: %s
+				descr:   Generated object %d
+				admin-c: PERSON-%d-DN42
+				tech-c:  PERSON-%d-DN42
+				mnt-by:  MNTNER-%d-MNT
+				remarks: A remark that runs long enough to be close to the
+				         length of a real registry object remark
+				source:  DN42
+			`, schema, name, i, i, i, i)))
+			path := filepath.Join(dir, schema, name)
+			if err := os.WriteFile(path, []byte(dom.String()+"\n"), 0644); err != nil {
+				b.Fatal(err)
+			}
+		}
+	}
+
+	run := func(name string, workers int) {
+		b.Run(name, func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				if _, err := rpsl.LoadDir(context.Background(), dir, workers); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+	run("sequential", 1)
+	run("parallel", runtime.GOMAXPROCS(0))
+}
@@ -0,0 +1,387 @@
+package rpsl
+
+import (
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// Snapshot is an immutable view of a registry held in memory. Objects
+// returned share storage with the snapshot and are copied on their first
+// change, so readers never see changes made by others.
+type Snapshot struct {
+	schemas *Schemas
+	objects map[string]map[string]*Object
+
+	// refs indexes the objects that refer to each object.
+	refs map[objectKey][]objectKey
+}
+
+var _ Fetcher = (*Snapshot)(nil)
+var _ Indexer = (*Snapshot)(nil)
+var _ Lister = (*Snapshot)(nil)
+
+// NewSnapshot holding copies of the objects in lis. Schemas are parsed from
+// the schema objects within and applied to the others.
+func NewSnapshot(lis ListObject) (*Snapshot, error) {
+	copies := make(ListObject, len(lis))
+	for i, dom := range lis {
+		copies[i] = dom.Clone()
+	}
+
+	return newSnapshot(copies)
+}
+
+// newSnapshot indexes lis without copying. The objects must not be changed
+// afterwards.
+func newSnapshot(lis ListObject) (*Snapshot, error) {
+	schemas, err := ParseSchemas(lis)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Snapshot{
+		schemas: schemas,
+		objects: make(map[string]map[string]*Object),
+		refs:    make(map[objectKey][]objectKey),
+	}
+	e := s.edit()
+	for _, dom := range lis {
+		dom.schema = nil
+		schemas.Apply(dom)
+		e.put(dom)
+	}
+
+	return s, nil
+}
+
+// Schemas parsed from the schema objects in the snapshot.
+func (s *Snapshot) Schemas() *Schemas {
+	return s.schemas
+}
+
+func (s *Snapshot) LoadObject(schema, name string) (*Object, error) {
+	dom, ok := s.objects[schema][FileName(name)]
+	if !ok {
+		return nil, NotFound
+	}
+	return dom.view(), nil
+}
+
+// FindObject returns objects in any schema whose name matches search.
+func (s *Snapshot) FindObject(search string) ([]*Object, error) {
+	names := make([]string, 0, len(s.objects))
+	for name := range s.objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var lis []*Object
+	for _, schema := range names {
+		if dom, ok := s.objects[schema][FileName(search)]; ok {
+			lis = append(lis, dom.view())
+		}
+	}
+
+	if len(lis) == 0 {
+		return nil, NotFound
+	}
+
+	return lis, nil
+}
+
+// ListObjects in schema sorted by name.
+func (s *Snapshot) ListObjects(schema string) (ListObject, error) {
+	objects := s.objects[schema]
+	names := make([]string, 0, len(objects))
+	for name := range objects {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	lis := make(ListObject, len(names))
+	for i, name := range names {
+		lis[i] = objects[name].view()
+	}
+
+	return lis, nil
+}
+
+// Referrers returns the objects that refer to schema and name, sorted by
+// schema and name.
+func (s *Snapshot) Referrers(schema, name string) ListObject {
+	keys := append([]objectKey(nil), s.refs[objectKey{schema, FileName(name)}]...)
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i][0] != keys[j][0] {
+			return keys[i][0] < keys[j][0]
+		}
+		return keys[i][1] < keys[j][1]
+	})
+
+	lis := make(ListObject, 0, len(keys))
+	for _, key := range keys {
+		if dom, ok := s.objects[key[0]][key[1]]; ok {
+			lis = append(lis, dom.view())
+		}
+	}
+
+	return lis
+}
+
+// all returns views of every object in the snapshot.
+func (s *Snapshot) all() ListObject {
+	var lis ListObject
+	for _, objects := range s.objects {
+		for _, dom := range objects {
+			lis = append(lis, dom.view())
+		}
+	}
+	return lis
+}
+
+// Memory is a registry held in memory. It is safe for concurrent use: reads
+// are served from the current snapshot without locking and each change
+// builds a new snapshot that replaces it atomically.
+type Memory struct {
+	mu   sync.Mutex // serialises changes
+	snap atomic.Value
+}
+
+var _ Store = (*Memory)(nil)
+var _ Indexer = (*Memory)(nil)
+var _ Lister = (*Memory)(nil)
+
+// NewMemory returns a registry holding copies of the objects in lis. Schemas
+// are parsed from the schema objects within and applied to the others.
+func NewMemory(lis ListObject) (*Memory, error) {
+	s, err := NewSnapshot(lis)
+	if err != nil {
+		return nil, err
+	}
+
+	return newMemory(s), nil
+}
+
+func newMemory(s *Snapshot) *Memory {
+	m := &Memory{}
+	m.snap.Store(s)
+	return m
+}
+
+// Snapshot returns the current state of the registry. It is not affected by
+// later changes.
+func (m *Memory) Snapshot() *Snapshot {
+	return m.snap.Load().(*Snapshot)
+}
+
+// Swap replaces the registry with s and returns the previous snapshot.
+// Readers holding the previous snapshot are unaffected.
+func (m *Memory) Swap(s *Snapshot) *Snapshot {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	old := m.Snapshot()
+	m.snap.Store(s)
+
+	return old
+}
+
+// Schemas of the current snapshot.
+func (m *Memory) Schemas() *Schemas {
+	return m.Snapshot().Schemas()
+}
+
+func (m *Memory) LoadObject(schema, name string) (*Object, error) {
+	return m.Snapshot().LoadObject(schema, name)
+}
+
+// FindObject returns objects in any schema whose name matches search.
+func (m *Memory) FindObject(search string) ([]*Object, error) {
+	return m.Snapshot().FindObject(search)
+}
+
+// ListObjects in schema sorted by name.
+func (m *Memory) ListObjects(schema string) (ListObject, error) {
+	return m.Snapshot().ListObjects(schema)
+}
+
+// Referrers returns the objects that refer to schema and name.
+func (m *Memory) Referrers(schema, name string) ListObject {
+	return m.Snapshot().Referrers(schema, name)
+}
+
+// SaveObject adds or replaces a copy of dom.
+func (m *Memory) SaveObject(dom *Object) error {
+	return m.Apply([]*Change{{Type: ChangeModify, Object: dom}})
+}
+
+func (m *Memory) DeleteObject(schema, name string) error {
+	return m.update([]memoryOp{{key: objectKey{schema, name}}})
+}
+
+// Apply changes to the registry as a single new snapshot. Creates and
+// modifies both save a copy of the object. Deleting an object that does not
+// exist returns NotFound and no change is made. Changing a schema object
+// reapplies the schemas to every object held.
+func (m *Memory) Apply(changes []*Change) error {
+	schemas := m.Snapshot().Schemas()
+
+	ops := make([]memoryOp, len(changes))
+	for i, c := range changes {
+		dom := c.Object.view()
+		dom.schema = nil
+		schemas.Apply(dom)
+
+		if c.Type == ChangeDelete {
+			ops[i].key = objectKey{dom.Schema(), dom.Name()}
+		} else {
+			ops[i].dom = dom.Clone()
+		}
+	}
+
+	return m.update(ops)
+}
+
+// memoryOp saves dom or, when nil, deletes key.
+type memoryOp struct {
+	key objectKey
+	dom *Object
+}
+
+func (m *Memory) update(ops []memoryOp) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	cur := m.Snapshot()
+	e := cur.copy().edit()
+
+	rebuild := false
+	for _, op := range ops {
+		if op.dom == nil {
+			if !e.remove(op.key[0], op.key[1]) {
+				return NotFound
+			}
+			rebuild = rebuild || op.key[0] == "schema"
+			continue
+		}
+
+		// Schemas may have changed since Apply.
+		op.dom.schema = nil
+		cur.schemas.Apply(op.dom)
+		e.put(op.dom)
+		rebuild = rebuild || op.dom.Schema() == "schema"
+	}
+
+	next := e.Snapshot
+	if rebuild {
+		var err error
+		if next, err = newSnapshot(next.all()); err != nil {
+			return err
+		}
+	}
+	m.snap.Store(next)
+
+	return nil
+}
+
+// copy returns a snapshot sharing the objects of s.
+func (s *Snapshot) copy() *Snapshot {
+	next := &Snapshot{
+		schemas: s.schemas,
+		objects: make(map[string]map[string]*Object, len(s.objects)),
+		refs:    make(map[objectKey][]objectKey, len(s.refs)),
+	}
+	for name, objects := range s.objects {
+		next.objects[name] = objects
+	}
+	for key, refs := range s.refs {
+		next.refs[key] = refs
+	}
+
+	return next
+}
+
+// snapshotEdit changes a snapshot that has not been published. Indexes of
+// objects shared with another snapshot are copied before they are changed.
+type snapshotEdit struct {
+	*Snapshot
+	owned   map[string]bool
+	lookups map[string][]string
+}
+
+func (s *Snapshot) edit() *snapshotEdit {
+	return &snapshotEdit{Snapshot: s, owned: make(map[string]bool), lookups: make(map[string][]string)}
+}
+
+func (e *snapshotEdit) index(schema string) map[string]*Object {
+	if !e.owned[schema] {
+		objects := make(map[string]*Object, len(e.objects[schema])+1)
+		for name, dom := range e.objects[schema] {
+			objects[name] = dom
+		}
+		e.objects[schema] = objects
+		e.owned[schema] = true
+	}
+	return e.objects[schema]
+}
+
+// put adds or replaces dom and indexes its references.
+func (e *snapshotEdit) put(dom *Object) {
+	e.remove(dom.Schema(), dom.Name())
+
+	key := objectKey{dom.Schema(), FileName(dom.Name())}
+	e.index(key[0])[key[1]] = dom
+
+	for _, ref := range e.references(dom) {
+		e.refs[ref] = append(e.refs[ref][:len(e.refs[ref]):len(e.refs[ref])], key)
+	}
+}
+
+// remove deletes an object and its references, reporting if it existed.
+func (e *snapshotEdit) remove(schema, name string) bool {
+	key := objectKey{schema, FileName(name)}
+	dom, ok := e.objects[key[0]][key[1]]
+	if !ok {
+		return false
+	}
+	delete(e.index(key[0]), key[1])
+
+	for _, ref := range e.references(dom) {
+		keys := make([]objectKey, 0, len(e.refs[ref]))
+		for _, k := range e.refs[ref] {
+			if k != key {
+				keys = append(keys, k)
+			}
+		}
+		if len(keys) == 0 {
+			delete(e.refs, ref)
+		} else {
+			e.refs[ref] = keys
+		}
+	}
+
+	return true
+}
+
+// references returns the keys of the objects dom may refer to.
+func (e *snapshotEdit) references(dom *Object) []objectKey {
+	var lis []objectKey
+	seen := make(map[objectKey]bool)
+	for _, arg := range dom.References() {
+		for _, choice := range arg.Choices {
+			schemas, ok := e.lookups[choice]
+			if !ok {
+				schemas = e.schemas.Lookups(choice)
+				e.lookups[choice] = schemas
+			}
+			for _, schema := range schemas {
+				key := objectKey{schema, FileName(arg.Value)}
+				if !seen[key] {
+					seen[key] = true
+					lis = append(lis, key)
+				}
+			}
+		}
+	}
+	return lis
+}
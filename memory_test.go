@@ -0,0 +1,203 @@
+package rpsl_test
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestMemory(t *testing.T) {
+	is := is.New(t)
+
+	m, err := rpsl.NewMemory(rpsl.ParseAll(strings.NewReader(cleanDoc(`
+		inetnum: 172.21.64.0 - 172.21.64.7
+		cidr:    172.21.64.0/29
+		mnt-by:  XUU-MNT
+	`))))
+	is.NoErr(err)
+
+	_, err = m.LoadObject("inetnum", "172.21.64.0/29")
+	is.Equal(err, rpsl.NotFound)
+
+	// Schemas saved later are applied to the objects already held.
+	is.NoErr(m.SaveObject(rpsl.ParseObject(cleanDoc(`
+		schema: INETNUM-SCHEMA
+		key:    inetnum required single
+		key:    cidr    required single primary
+	`))))
+
+	dom, err := m.LoadObject("inetnum", "172.21.64.0_29")
+	is.NoErr(err)
+	is.Equal(dom.Name(), "172.21.64.0/29")
+
+	is.NoErr(m.DeleteObject("inetnum", "172.21.64.0/29"))
+	is.Equal(m.DeleteObject("inetnum", "172.21.64.0/29"), rpsl.NotFound)
+
+	lis, err := m.ListObjects("inetnum")
+	is.NoErr(err)
+	is.Equal(len(lis), 0)
+}
+
+func TestSnapshot(t *testing.T) {
+	is := is.New(t)
+
+	lis := rpsl.ParseAll(strings.NewReader(cleanDoc(txtAllObjects)))
+	m, err := rpsl.NewMemory(lis)
+	is.NoErr(err)
+
+	// Changing the objects used to build the registry does not change it.
+	for _, dom := range lis {
+		if dom.Schema() == "mntner" {
+			dom.Add("remarks", "changed")
+		}
+	}
+
+	mnt, err := m.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(mnt.Get("remarks"), nil)
+
+	// Changing a loaded object copies it first.
+	mnt.Set("mnt-by", "OTHER-MNT")
+	mnt.Add("remarks", "changed")
+	mnt.Delete("descr")
+
+	again, err := m.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(again.Get("mnt-by").Text(), "XUU-MNT")
+	is.Equal(again.Get("remarks"), nil)
+	is.True(again.Get("descr") != nil)
+
+	// A snapshot does not see later changes.
+	snap := m.Snapshot()
+	is.NoErr(m.SaveObject(mnt))
+	is.NoErr(m.DeleteObject("person", "XUU-DN42"))
+
+	old, err := snap.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(old.Get("mnt-by").Text(), "XUU-MNT")
+	_, err = snap.LoadObject("person", "XUU-DN42")
+	is.NoErr(err)
+
+	cur, err := m.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(cur.Get("mnt-by").Text(), "OTHER-MNT")
+	_, err = m.LoadObject("person", "XUU-DN42")
+	is.Equal(err, rpsl.NotFound)
+
+	prev := m.Swap(snap)
+	is.True(prev != snap)
+	is.True(m.Snapshot() == snap)
+	_, err = m.LoadObject("person", "XUU-DN42")
+	is.NoErr(err)
+}
+
+// TestReloadRace reads and changes objects while the registry is reloaded
+// and written to. Run with -race.
+func TestReloadRace(t *testing.T) {
+	is := is.New(t)
+
+	lis := rpsl.ParseAll(strings.NewReader(cleanDoc(txtAllObjects)))
+	m, err := rpsl.NewMemory(lis)
+	is.NoErr(err)
+
+	r := rpsl.NewRPSL(rpsl.WithFetcher(m))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 200; j++ {
+				dom, err := r.LoadObject("mntner", "XUU-MNT")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				dom.Add("remarks", "reader")
+				dom.Set("mnt-by", "READER-MNT")
+				_ = dom.String()
+
+				if _, err := r.FindObject("XUU-DN42"); err != nil && err != rpsl.NotFound {
+					t.Error(err)
+					return
+				}
+				if _, err := r.ListObjects("person"); err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for j := 0; j < 50; j++ {
+			next, err := rpsl.NewMemory(lis)
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			r.Reload(rpsl.WithFetcher(next))
+
+			dom := rpsl.ParseObject(fmt.Sprintf("person: Writer\nnic-hdl: XUU-DN42\nremarks: %d", j))
+			if err := r.SaveObject(dom); err != nil {
+				t.Error(err)
+				return
+			}
+			if err := r.DeleteObject("person", "XUU-DN42"); err != nil && err != rpsl.NotFound {
+				t.Error(err)
+				return
+			}
+		}
+	}()
+
+	wg.Wait()
+
+	dom, err := r.LoadObject("mntner", "XUU-MNT")
+	is.NoErr(err)
+	is.Equal(dom.Get("mnt-by").Text(), "XUU-MNT")
+}
+
+func TestReferrers(t *testing.T) {
+	is := is.New(t)
+
+	m, err := rpsl.NewMemory(rpsl.ParseAll(strings.NewReader(cleanDoc(txtAllObjects))))
+	is.NoErr(err)
+
+	names := func(lis rpsl.ListObject) []string {
+		s := make([]string, len(lis))
+		for i, dom := range lis {
+			s[i] = dom.Schema() + "/" + dom.Name()
+		}
+		return s
+	}
+
+	is.Equal(names(m.Referrers("mntner", "XUU-MNT")), []string{
+		"inetnum/172.21.64.0/29",
+		"mntner/XUU-MNT",
+		"person/XUU-DN42",
+		"role/SOURIS-DN42",
+	})
+	is.Equal(names(m.Referrers("person", "XUU-DN42")), []string{"role/SOURIS-DN42"})
+
+	is.NoErr(m.DeleteObject("role", "SOURIS-DN42"))
+	is.Equal(len(m.Referrers("person", "XUU-DN42")), 0)
+
+	inet, err := m.LoadObject("inetnum", "172.21.64.0/29")
+	is.NoErr(err)
+	inet.Set("mnt-by", "DN42-MNT")
+	is.NoErr(m.SaveObject(inet))
+
+	is.Equal(names(m.Referrers("mntner", "XUU-MNT")), []string{"mntner/XUU-MNT", "person/XUU-DN42"})
+	is.Equal(names(m.Referrers("mntner", "DN42-MNT"))[:4], []string{
+		"inetnum/0.0.0.0/0",
+		"inetnum/172.21.64.0/29",
+		"mntner/DN42-MNT",
+		"registry/DN42",
+	})
+}
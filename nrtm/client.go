@@ -0,0 +1,124 @@
+package nrtm
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+// Client queries an NRTMv3 server for changes to a source.
+type Client struct {
+	// Addr of the server as host:port.
+	Addr   string
+	Source string
+
+	// Schemas applied to received objects so that they are stored under
+	// their primary key. Objects of unknown schemas or that fail validation
+	// are rejected. Optional.
+	Schemas *rpsl.Schemas
+}
+
+// Fetch the entries from first to last. A last of zero requests every entry
+// up to the latest serial.
+func (c *Client) Fetch(ctx context.Context, first, last uint64) ([]*Entry, error) {
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", c.Addr)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	end := "LAST"
+	if last > 0 {
+		end = fmt.Sprint(last)
+	}
+	if _, err := fmt.Fprintf(conn, "-g %s:3:%d-%s\n", c.Source, first, end); err != nil {
+		return nil, err
+	}
+
+	r := bufio.NewReader(conn)
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return nil, fmt.Errorf("nrtm: %w", err)
+		}
+		line = strings.TrimSpace(line)
+
+		switch {
+		case line == "":
+			continue
+		case strings.HasPrefix(line, "%ERROR"):
+			return nil, fmt.Errorf("nrtm: %s", strings.TrimSpace(strings.TrimPrefix(line, "%ERROR:")))
+		case strings.HasPrefix(line, "%START"):
+			fields := strings.Fields(line)
+			if len(fields) != 5 || fields[2] != "3" {
+				return nil, fmt.Errorf("nrtm: unsupported response %q", line)
+			}
+			return readEntries(r, "%END")
+		case strings.HasPrefix(line, "%"):
+			continue
+		default:
+			return nil, fmt.Errorf("nrtm: unexpected line %q", line)
+		}
+	}
+}
+
+// Apply entries to store. Deleting an object that does not exist is not an
+// error. Entries are applied in order up to the first that fails.
+func (c *Client) Apply(store rpsl.Store, entries []*Entry) error {
+	for _, e := range entries {
+		dom := e.Object
+		if err := c.check(e); err != nil {
+			return fmt.Errorf("nrtm: serial %d: %w", e.Serial, err)
+		}
+
+		var err error
+		switch e.Op {
+		case Add:
+			err = store.SaveObject(dom)
+		case Del:
+			err = store.DeleteObject(dom.Schema(), dom.Name())
+			if err == rpsl.NotFound {
+				err = nil
+			}
+		}
+		if err != nil {
+			return fmt.Errorf("nrtm: serial %d: %w", e.Serial, err)
+		}
+	}
+
+	return nil
+}
+
+// check applies the schemas to the object of an entry. Objects of unknown
+// schemas and additions that fail validation are rejected.
+func (c *Client) check(e *Entry) error {
+	dom := e.Object
+	if c.Schemas != nil {
+		c.Schemas.Apply(dom)
+	}
+	if dom.Name() == "" {
+		return fmt.Errorf("%s: missing object name", dom.Schema())
+	}
+	if c.Schemas == nil {
+		return nil
+	}
+
+	schema := c.Schemas.Get(dom.Schema())
+	if schema == nil {
+		return fmt.Errorf("%s %s: unknown schema", dom.Schema(), dom.Name())
+	}
+	if e.Op == Add {
+		return schema.Validate(dom)
+	}
+
+	return nil
+}
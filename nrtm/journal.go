@@ -0,0 +1,256 @@
+// Package nrtm mirrors registry changes using the NRTMv3 protocol.
+//
+// A Journal assigns a serial to every object written to or deleted from a
+// store. A Server answers "-g SOURCE:3:first-last" queries with the journal
+// entries in that range and a Client applies them to a local store.
+package nrtm
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+// Op is the operation of a journal entry.
+type Op int
+
+const (
+	Add Op = iota
+	Del
+)
+
+func (op Op) String() string {
+	if op == Del {
+		return "DEL"
+	}
+	return "ADD"
+}
+
+// Entry is a serial numbered change to an object.
+type Entry struct {
+	Serial uint64
+	Op     Op
+	Object *rpsl.Object
+}
+
+func (e *Entry) String() string {
+	return fmt.Sprintf("%s %d\n\n%s\n", e.Op, e.Serial, e.Object)
+}
+
+// RangeError is returned for serials not held in a journal.
+type RangeError struct {
+	First, Last uint64
+}
+
+func (e *RangeError) Error() string {
+	if e.Last < e.First {
+		return "invalid range: journal is empty"
+	}
+	return fmt.Sprintf("invalid range: Not within %d-%d", e.First, e.Last)
+}
+
+// Journal is an in memory log of changes for a source.
+type Journal struct {
+	Source string
+
+	mu      sync.RWMutex
+	first   uint64
+	entries []*Entry
+}
+
+// NewJournal for source starting at serial 1.
+func NewJournal(source string) *Journal {
+	return &Journal{Source: source, first: 1}
+}
+
+// Append a change and return its serial.
+func (j *Journal) Append(op Op, dom *rpsl.Object) uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	e := &Entry{Serial: j.first + uint64(len(j.entries)), Op: op, Object: dom.Clone()}
+	j.entries = append(j.entries, e)
+
+	return e.Serial
+}
+
+// Serials returns the first and last serial held. Last is less than first
+// when the journal is empty.
+func (j *Journal) Serials() (first, last uint64) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	return j.first, j.first + uint64(len(j.entries)) - 1
+}
+
+// Range returns the entries from first to last inclusive.
+func (j *Journal) Range(first, last uint64) ([]*Entry, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	end := j.first + uint64(len(j.entries)) - 1
+	if first < j.first || last > end || first > last {
+		return nil, &RangeError{First: j.first, Last: end}
+	}
+
+	lis := make([]*Entry, last-first+1)
+	copy(lis, j.entries[first-j.first:])
+
+	return lis, nil
+}
+
+// WriteTo writes every entry in the NRTMv3 operation format.
+func (j *Journal) WriteTo(w io.Writer) (int64, error) {
+	j.mu.RLock()
+	defer j.mu.RUnlock()
+
+	var n int64
+	for _, e := range j.entries {
+		m, err := io.WriteString(w, e.String()+"\n")
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+
+	return n, nil
+}
+
+// ReadJournal loads a journal written by WriteTo. Serials must be consecutive.
+func ReadJournal(source string, r io.Reader) (*Journal, error) {
+	entries, err := readEntries(bufio.NewReader(r), "")
+	if err != nil {
+		return nil, err
+	}
+
+	j := NewJournal(source)
+	if len(entries) > 0 {
+		j.first = entries[0].Serial
+	}
+	for i, e := range entries {
+		if e.Serial != j.first+uint64(i) {
+			return nil, fmt.Errorf("nrtm: serial %d out of sequence", e.Serial)
+		}
+	}
+	j.entries = entries
+
+	return j, nil
+}
+
+// readEntries parses operations until EOF or a line starting with end.
+func readEntries(r *bufio.Reader, end string) ([]*Entry, error) {
+	var (
+		lis     []*Entry
+		current *Entry
+		lines   []string
+	)
+	flush := func() {
+		if current != nil && len(lines) > 0 {
+			current.Object = rpsl.ParseObject(strings.Join(lines, "\n"))
+			lis = append(lis, current)
+			current, lines = nil, nil
+		}
+	}
+
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil && err != io.EOF {
+			return nil, err
+		}
+		if err == io.EOF && line == "" {
+			break
+		}
+		line = strings.TrimRight(line, "\r\n")
+
+		switch {
+		case end != "" && strings.HasPrefix(line, end):
+			flush()
+			return lis, nil
+
+		case strings.HasPrefix(line, "ADD ") || strings.HasPrefix(line, "DEL "):
+			flush()
+			if current != nil {
+				return nil, fmt.Errorf("nrtm: %s without object", current.Op)
+			}
+			serial, err := strconv.ParseUint(strings.TrimSpace(line[4:]), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("nrtm: invalid operation %q", line)
+			}
+			current = &Entry{Serial: serial, Op: Add}
+			if line[:3] == "DEL" {
+				current.Op = Del
+			}
+
+		case line == "":
+			flush()
+
+		case current != nil:
+			lines = append(lines, line)
+
+		case strings.HasPrefix(line, "%"):
+			// Comments between operations.
+
+		default:
+			return nil, fmt.Errorf("nrtm: unexpected line %q", line)
+		}
+
+		if err == io.EOF {
+			break
+		}
+	}
+	flush()
+
+	if end != "" {
+		return nil, fmt.Errorf("nrtm: missing %s", end)
+	}
+	if current != nil {
+		return nil, fmt.Errorf("nrtm: %s %d without object", current.Op, current.Serial)
+	}
+
+	return lis, nil
+}
+
+// Store returns a store that records every write to store in the journal.
+// The returned store is also a Lister when store is.
+func (j *Journal) Store(store rpsl.Store) rpsl.Store {
+	s := &journalStore{Store: store, journal: j}
+	if list, ok := store.(rpsl.Lister); ok {
+		return &journalListStore{journalStore: s, Lister: list}
+	}
+	return s
+}
+
+type journalStore struct {
+	rpsl.Store
+	journal *Journal
+}
+
+type journalListStore struct {
+	*journalStore
+	rpsl.Lister
+}
+
+func (s *journalStore) SaveObject(dom *rpsl.Object) error {
+	if err := s.Store.SaveObject(dom); err != nil {
+		return err
+	}
+	s.journal.Append(Add, dom)
+	return nil
+}
+
+func (s *journalStore) DeleteObject(schema, name string) error {
+	dom, err := s.Store.LoadObject(schema, name)
+	if err != nil {
+		return err
+	}
+	if err := s.Store.DeleteObject(schema, name); err != nil {
+		return err
+	}
+	s.journal.Append(Del, dom)
+	return nil
+}
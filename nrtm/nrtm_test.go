@@ -0,0 +1,175 @@
+package nrtm_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/nrtm"
+)
+
+// registry copies the schemas from the test registry to a temp directory.
+func registry(t *testing.T) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	src := filepath.Join("..", "testdata", "registry", "schema")
+	files, err := os.ReadDir(src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(dir, "schema"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	for _, f := range files {
+		b, err := os.ReadFile(filepath.Join(src, f.Name()))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "schema", f.Name()), b, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func schemas(t *testing.T, r *rpsl.RPSL) *rpsl.Schemas {
+	t.Helper()
+
+	lis, err := r.ListObjects("schema")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := rpsl.ParseSchemas(lis)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func object(s *rpsl.Schemas, txt string) *rpsl.Object {
+	dom := rpsl.ParseObject(txt)
+	s.Apply(dom)
+	return dom
+}
+
+func TestJournal(t *testing.T) {
+	is := is.New(t)
+
+	origin := rpsl.NewRPSL(rpsl.WithRPSLDir(registry(t)))
+	s := schemas(t, origin)
+
+	j := nrtm.NewJournal("DN42")
+	store := j.Store(origin)
+	_, ok := store.(rpsl.Lister)
+	is.True(ok)
+
+	mnt := object(s, "mntner: XUU-MNT\nmnt-by: XUU-MNT\nsource: DN42")
+	is.NoErr(store.SaveObject(mnt))
+	is.NoErr(store.SaveObject(object(s, "inetnum: 172.21.64.0 - 172.21.64.7\ncidr: 172.21.64.0/29\nmnt-by: XUU-MNT\nsource: DN42")))
+	is.NoErr(store.DeleteObject("inetnum", "172.21.64.0/29"))
+	is.Equal(store.DeleteObject("inetnum", "172.21.64.0/29"), rpsl.NotFound)
+
+	// Later changes to a saved object don't rewrite the journal.
+	mnt.Add("remarks", "changed")
+
+	first, last := j.Serials()
+	is.Equal(first, uint64(1))
+	is.Equal(last, uint64(3))
+
+	lis, err := j.Range(2, 3)
+	is.NoErr(err)
+	is.Equal(len(lis), 2)
+	is.Equal(lis[0].Op, nrtm.Add)
+	is.Equal(lis[1].Op, nrtm.Del)
+	is.Equal(lis[1].Object.Get("cidr").Text(), "172.21.64.0/29")
+
+	_, err = j.Range(2, 4)
+	var rerr *nrtm.RangeError
+	is.True(errors.As(err, &rerr))
+	is.Equal(err.Error(), "invalid range: Not within 1-3")
+
+	var buf bytes.Buffer
+	_, err = j.WriteTo(&buf)
+	is.NoErr(err)
+
+	read, err := nrtm.ReadJournal("DN42", &buf)
+	is.NoErr(err)
+	first, last = read.Serials()
+	is.Equal(first, uint64(1))
+	is.Equal(last, uint64(3))
+
+	lis, err = read.Range(1, 1)
+	is.NoErr(err)
+	is.Equal(lis[0].String(), "ADD 1\n\nmntner:             XUU-MNT\nmnt-by:             XUU-MNT\nsource:             DN42\n")
+}
+
+func TestMirror(t *testing.T) {
+	is := is.New(t)
+
+	origin := rpsl.NewRPSL(rpsl.WithRPSLDir(registry(t)))
+	s := schemas(t, origin)
+
+	j := nrtm.NewJournal("DN42")
+	store := j.Store(origin)
+	is.NoErr(store.SaveObject(object(s, "mntner: XUU-MNT\nmnt-by: XUU-MNT\nsource: DN42")))
+	is.NoErr(store.SaveObject(object(s, "inetnum: 172.21.64.0 - 172.21.64.7\ncidr: 172.21.64.0/29\nmnt-by: XUU-MNT\nsource: DN42")))
+	is.NoErr(store.SaveObject(object(s, "person: Xuu\nnic-hdl: XUU-DN42\nmnt-by: XUU-MNT\nsource: DN42")))
+	is.NoErr(store.DeleteObject("person", "XUU-DN42"))
+
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	is.NoErr(err)
+	defer l.Close()
+
+	srv := &nrtm.Server{Journal: j, Timeout: time.Second}
+	go srv.Serve(l)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	mirror := rpsl.NewRPSL(rpsl.WithRPSLDir(registry(t)))
+	c := &nrtm.Client{Addr: l.Addr().String(), Source: "DN42", Schemas: s}
+
+	entries, err := c.Fetch(ctx, 1, 0)
+	is.NoErr(err)
+	is.Equal(len(entries), 4)
+	is.Equal(entries[3].Serial, uint64(4))
+	is.NoErr(c.Apply(mirror, entries))
+
+	dom, err := mirror.LoadObject("inetnum", "172.21.64.0/29")
+	is.NoErr(err)
+	is.Equal(dom.Get("mnt-by").Text(), "XUU-MNT")
+
+	_, err = mirror.LoadObject("person", "XUU-DN42")
+	is.Equal(err, rpsl.NotFound)
+
+	// Replaying a deletion of a missing object is not an error.
+	is.NoErr(c.Apply(mirror, entries[3:]))
+
+	// Objects of unknown schemas or that fail validation are not applied.
+	for _, txt := range []string{"../escaped: pwned", "mntner: ..\nsource: DN42", "person: Xuu"} {
+		err = c.Apply(mirror, []*nrtm.Entry{{Serial: 5, Op: nrtm.Add, Object: rpsl.ParseObject(txt)}})
+		is.True(err != nil)
+	}
+	err = c.Apply(mirror, []*nrtm.Entry{{Serial: 5, Op: nrtm.Del, Object: rpsl.ParseObject("../escaped: pwned")}})
+	is.Equal(err.Error(), "nrtm: serial 5: ../escaped pwned: unknown schema")
+
+	entries, err = c.Fetch(ctx, 2, 3)
+	is.NoErr(err)
+	is.Equal(len(entries), 2)
+
+	_, err = c.Fetch(ctx, 3, 9)
+	is.Equal(err.Error(), "nrtm: 401: invalid range: Not within 1-4")
+
+	c.Source = "RIPE"
+	_, err = c.Fetch(ctx, 1, 0)
+	is.Equal(err.Error(), "nrtm: 403: unknown source RIPE")
+}
@@ -0,0 +1,102 @@
+package nrtm
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Server answers NRTMv3 queries from a journal.
+type Server struct {
+	Journal *Journal
+
+	// Timeout for reading the query. Zero means no timeout.
+	Timeout time.Duration
+}
+
+// Serve accepts connections on l until it is closed.
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go s.ServeConn(conn)
+	}
+}
+
+// ServeConn answers a single query on conn and closes it.
+func (s *Server) ServeConn(conn net.Conn) {
+	defer conn.Close()
+
+	if s.Timeout > 0 {
+		conn.SetReadDeadline(time.Now().Add(s.Timeout))
+	}
+	query, err := bufio.NewReader(conn).ReadString('\n')
+	if err != nil && err != io.EOF {
+		return
+	}
+
+	w := bufio.NewWriter(conn)
+	s.answer(w, strings.TrimSpace(query))
+	w.Flush()
+}
+
+func (s *Server) answer(w io.Writer, query string) {
+	source, first, last, err := s.parseQuery(query)
+	if err != nil {
+		fmt.Fprintf(w, "%%ERROR:%s\n", err)
+		return
+	}
+
+	entries, err := s.Journal.Range(first, last)
+	if err != nil {
+		fmt.Fprintf(w, "%%ERROR:401: %s\n", err)
+		return
+	}
+
+	fmt.Fprintf(w, "%%START Version: 3 %s %d-%d\n\n", source, first, last)
+	for _, e := range entries {
+		fmt.Fprintf(w, "%s\n", e)
+	}
+	fmt.Fprintf(w, "%%END %s\n", source)
+}
+
+// parseQuery parses "-g SOURCE:3:first-last" where last may be LAST.
+func (s *Server) parseQuery(query string) (source string, first, last uint64, err error) {
+	fields := strings.Fields(query)
+	if len(fields) != 2 || fields[0] != "-g" {
+		return "", 0, 0, errors.New("405: no flags or query specified")
+	}
+
+	sp := strings.Split(fields[1], ":")
+	if len(sp) != 3 {
+		return "", 0, 0, errors.New("405: syntax error")
+	}
+	if !strings.EqualFold(sp[0], s.Journal.Source) {
+		return "", 0, 0, fmt.Errorf("403: unknown source %s", sp[0])
+	}
+	if sp[1] != "3" {
+		return "", 0, 0, fmt.Errorf("405: unsupported version %s", sp[1])
+	}
+
+	rng := strings.SplitN(sp[2], "-", 2)
+	if len(rng) != 2 {
+		return "", 0, 0, errors.New("405: syntax error")
+	}
+	if first, err = strconv.ParseUint(rng[0], 10, 64); err != nil {
+		return "", 0, 0, errors.New("405: syntax error")
+	}
+	if strings.EqualFold(rng[1], "LAST") {
+		_, last = s.Journal.Serials()
+	} else if last, err = strconv.ParseUint(rng[1], 10, 64); err != nil {
+		return "", 0, 0, errors.New("405: syntax error")
+	}
+
+	return s.Journal.Source, first, last, nil
+}
@@ -0,0 +1,682 @@
+package rpsl
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Policy is a parsed import, export or default policy as described in
+// RFC 2622 and RFC 4012.
+type Policy struct {
+	Protocol string
+	Into     string
+	AFI      []string
+	Factors  []*PolicyFactor
+
+	// Op joins the Next policy with "except" or "refine".
+	Op   string
+	Next *Policy
+}
+
+// PolicyFactor is a list of peerings with the filter applied to them.
+type PolicyFactor struct {
+	Peerings []*PolicyPeering
+
+	// Filter is the accept, announce or networks expression.
+	Filter *PolicyExpr
+
+	// peer and filter are the keywords of the policy attribute, such as
+	// from and accept for import.
+	peer, filter string
+}
+
+// PolicyPeering is a peering with the actions applied to it.
+type PolicyPeering struct {
+	// AS is the AS expression or peering-set name of the peer.
+	AS *PolicyExpr
+	// Remote router expression of the peer.
+	Remote *PolicyExpr
+	// Local router expression following "at".
+	Local *PolicyExpr
+
+	Actions []*PolicyAction
+}
+
+// PolicyAction modifies a route attribute, either by an operator such as
+// pref=10 or a method such as community.append(64512:1).
+type PolicyAction struct {
+	Attr   string
+	Method string
+	Op     string
+	Args   []string
+}
+
+// PolicyExpr is a boolean expression over AS numbers, set names, prefix
+// lists and AS path regular expressions. Leaf expressions only have a Value.
+type PolicyExpr struct {
+	// Op is one of AND, OR, NOT or EXCEPT.
+	Op    string
+	Args  []*PolicyExpr
+	Value string
+}
+
+// PolicyError describes where parsing a policy failed.
+type PolicyError struct {
+	Pos int
+	Msg string
+}
+
+func (e *PolicyError) Error() string {
+	return fmt.Sprintf("policy: column %d: %s", e.Pos+1, e.Msg)
+}
+
+// Policy parses the attribute value as a routing policy. The attribute name
+// selects import, export or default syntax.
+func (attr *Attribute) Policy() (*Policy, error) {
+	if attr == nil {
+		return nil, &PolicyError{Msg: "missing attribute"}
+	}
+
+	return ParsePolicy(attr.Name, attr.Text())
+}
+
+// ParsePolicy parses a policy for the named attribute: import, export,
+// default, mp-import, mp-export or mp-default.
+func ParsePolicy(name, text string) (*Policy, error) {
+	p := &policyParser{src: text, toks: lexPolicy(text), mp: strings.HasPrefix(name, "mp-")}
+
+	switch strings.TrimPrefix(name, "mp-") {
+	case "import":
+		p.peer, p.filter = "from", "accept"
+	case "export":
+		p.peer, p.filter = "to", "announce"
+	case "default":
+		p.peer, p.filter = "to", "networks"
+	default:
+		return nil, &PolicyError{Msg: fmt.Sprintf("unknown policy attribute %q", name)}
+	}
+
+	for _, t := range p.toks {
+		if t.kind == tokError {
+			return nil, &PolicyError{Pos: t.pos, Msg: t.text}
+		}
+	}
+
+	pol, err := p.parsePolicy(true)
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().punct(";") {
+		p.next()
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, p.errorf(t, "unexpected %q after policy", t.text)
+	}
+
+	return pol, nil
+}
+
+func (pol *Policy) String() string {
+	var b strings.Builder
+	if pol.Protocol != "" {
+		b.WriteString("protocol " + pol.Protocol + " ")
+	}
+	if pol.Into != "" {
+		b.WriteString("into " + pol.Into + " ")
+	}
+	if len(pol.AFI) > 0 {
+		b.WriteString("afi " + strings.Join(pol.AFI, ", ") + " ")
+	}
+
+	if len(pol.Factors) == 1 && pol.Next == nil {
+		b.WriteString(pol.Factors[0].String())
+	} else {
+		b.WriteString("{ ")
+		for _, f := range pol.Factors {
+			b.WriteString(f.String())
+			b.WriteString("; ")
+		}
+		b.WriteString("}")
+	}
+
+	if pol.Next != nil {
+		b.WriteString(" " + pol.Op + " " + pol.Next.String())
+	}
+
+	return b.String()
+}
+
+func (f *PolicyFactor) String() string {
+	lis := make([]string, 0, 2*len(f.Peerings)+2)
+	for _, p := range f.Peerings {
+		lis = append(lis, f.peer, p.String())
+	}
+	if f.Filter != nil {
+		lis = append(lis, f.filter, f.Filter.String())
+	}
+	return strings.Join(lis, " ")
+}
+
+func (p *PolicyPeering) String() string {
+	s := p.AS.String()
+	if p.Remote != nil {
+		s += " " + p.Remote.String()
+	}
+	if p.Local != nil {
+		s += " at " + p.Local.String()
+	}
+	if len(p.Actions) > 0 {
+		lis := make([]string, len(p.Actions))
+		for i, a := range p.Actions {
+			lis[i] = a.String()
+		}
+		s += " action " + strings.Join(lis, "; ") + ";"
+	}
+	return s
+}
+
+func (a *PolicyAction) String() string {
+	if a.Method != "" {
+		return a.Attr + "." + a.Method + "(" + strings.Join(a.Args, ", ") + ")"
+	}
+	return a.Attr + " " + a.Op + " " + strings.Join(a.Args, " ")
+}
+
+func (e *PolicyExpr) String() string {
+	if e == nil {
+		return ""
+	}
+
+	switch e.Op {
+	case "":
+		return e.Value
+	case "NOT":
+		return "NOT " + e.Args[0].group()
+	default:
+		return e.Args[0].group() + " " + e.Op + " " + e.Args[1].group()
+	}
+}
+
+func (e *PolicyExpr) group() string {
+	if e.Op == "" || e.Op == "NOT" {
+		return e.String()
+	}
+	return "(" + e.String() + ")"
+}
+
+type tokKind int
+
+const (
+	tokEOF tokKind = iota
+	tokWord
+	tokPunct
+	tokRegex
+	tokError
+)
+
+type policyToken struct {
+	kind tokKind
+	text string
+	pos  int
+}
+
+// keyword reports whether the token is the case insensitive keyword.
+func (t policyToken) keyword(names ...string) bool {
+	if t.kind != tokWord {
+		return false
+	}
+	for _, n := range names {
+		if strings.EqualFold(t.text, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t policyToken) punct(s string) bool {
+	return t.kind == tokPunct && t.text == s
+}
+
+func lexPolicy(s string) []policyToken {
+	var toks []policyToken
+
+	for i := 0; i < len(s); {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+
+		case c == '<':
+			j := strings.IndexByte(s[i:], '>')
+			if j < 0 {
+				return append(toks, policyToken{tokError, "unterminated as-path regular expression", i})
+			}
+			toks = append(toks, policyToken{tokRegex, s[i : i+j+1], i})
+			i += j + 1
+
+		case i+1 < len(s) && s[i+1] == '=' && strings.IndexByte(".+-*/=!", c) >= 0:
+			toks = append(toks, policyToken{tokPunct, s[i : i+2], i})
+			i += 2
+
+		case strings.IndexByte("{}();,=", c) >= 0:
+			toks = append(toks, policyToken{tokPunct, s[i : i+1], i})
+			i++
+
+		default:
+			j := i
+			for j < len(s) && strings.IndexByte(" \t\r\n<{}();,=", s[j]) < 0 {
+				if j+1 < len(s) && s[j+1] == '=' && strings.IndexByte(".+-*/!", s[j]) >= 0 {
+					break
+				}
+				j++
+			}
+			if j == i {
+				return append(toks, policyToken{tokError, fmt.Sprintf("unexpected character %q", c), i})
+			}
+			toks = append(toks, policyToken{tokWord, s[i:j], i})
+			i = j
+		}
+	}
+
+	return append(toks, policyToken{tokEOF, "end of policy", len(s)})
+}
+
+type policyParser struct {
+	src  string
+	toks []policyToken
+	pos  int
+	mp   bool
+
+	peer, filter string
+}
+
+func (p *policyParser) peek() policyToken {
+	return p.toks[p.pos]
+}
+
+func (p *policyParser) next() policyToken {
+	t := p.toks[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *policyParser) errorf(t policyToken, format string, args ...interface{}) error {
+	return &PolicyError{Pos: t.pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+func (p *policyParser) parsePolicy(top bool) (*Policy, error) {
+	pol := &Policy{}
+
+	if top {
+		if p.peek().keyword("protocol") {
+			p.next()
+			t := p.next()
+			if t.kind != tokWord {
+				return nil, p.errorf(t, "expected protocol name, found %q", t.text)
+			}
+			pol.Protocol = t.text
+		}
+		if p.peek().keyword("into") {
+			p.next()
+			t := p.next()
+			if t.kind != tokWord {
+				return nil, p.errorf(t, "expected protocol name after into, found %q", t.text)
+			}
+			pol.Into = t.text
+		}
+	}
+
+	if p.mp && p.peek().keyword("afi") {
+		p.next()
+		for {
+			t := p.next()
+			if t.kind != tokWord {
+				return nil, p.errorf(t, "expected address family, found %q", t.text)
+			}
+			pol.AFI = append(pol.AFI, strings.ToLower(t.text))
+			if !p.peek().punct(",") {
+				break
+			}
+			p.next()
+		}
+	}
+
+	if p.peek().punct("{") {
+		p.next()
+		for !p.peek().punct("}") {
+			f, err := p.parseFactor()
+			if err != nil {
+				return nil, err
+			}
+			pol.Factors = append(pol.Factors, f)
+
+			t := p.next()
+			if t.punct("}") {
+				p.pos--
+				break
+			}
+			if !t.punct(";") {
+				return nil, p.errorf(t, "expected ';' or '}' after policy term, found %q", t.text)
+			}
+		}
+		p.next()
+		if len(pol.Factors) == 0 {
+			return nil, p.errorf(p.peek(), "empty policy term")
+		}
+	} else {
+		f, err := p.parseFactor()
+		if err != nil {
+			return nil, err
+		}
+		pol.Factors = append(pol.Factors, f)
+	}
+
+	if t := p.peek(); t.keyword("except", "refine") {
+		p.next()
+		pol.Op = strings.ToLower(t.text)
+		next, err := p.parsePolicy(false)
+		if err != nil {
+			return nil, err
+		}
+		pol.Next = next
+	}
+
+	return pol, nil
+}
+
+func (p *policyParser) parseFactor() (*PolicyFactor, error) {
+	f := &PolicyFactor{peer: p.peer, filter: p.filter}
+
+	for p.peek().keyword(p.peer) {
+		p.next()
+		peering, err := p.parsePeering()
+		if err != nil {
+			return nil, err
+		}
+		f.Peerings = append(f.Peerings, peering)
+	}
+	if len(f.Peerings) == 0 {
+		t := p.peek()
+		return nil, p.errorf(t, "expected %q, found %q", p.peer, t.text)
+	}
+
+	t := p.peek()
+	if !t.keyword(p.filter) {
+		if p.filter == "networks" {
+			return f, nil
+		}
+		return nil, p.errorf(t, "expected %q, found %q", p.filter, t.text)
+	}
+	p.next()
+
+	filter, err := p.parseExpr(nil)
+	if err != nil {
+		return nil, err
+	}
+	f.Filter = filter
+
+	return f, nil
+}
+
+var policyKeywords = []string{
+	"from", "to", "at", "action", "accept", "announce", "networks",
+	"except", "refine", "afi", "protocol", "into",
+}
+
+func (p *policyParser) parsePeering() (*PolicyPeering, error) {
+	peering := &PolicyPeering{}
+
+	as, err := p.parseExpr(isASTerm)
+	if err != nil {
+		return nil, err
+	}
+	peering.AS = as
+
+	if t := p.peek(); (t.kind == tokWord || t.punct("(")) && !t.keyword(policyKeywords...) {
+		if peering.Remote, err = p.parseExpr(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek().keyword("at") {
+		p.next()
+		if peering.Local, err = p.parseExpr(nil); err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek().keyword("action") {
+		p.next()
+		if peering.Actions, err = p.parseActions(); err != nil {
+			return nil, err
+		}
+	}
+
+	return peering, nil
+}
+
+// isASTerm reports whether a word can be part of an AS expression.
+func isASTerm(s string) bool {
+	s = strings.ToUpper(s)
+	if s == "ANY" || s == "PEERAS" || strings.HasPrefix(s, "PRNG-") {
+		return true
+	}
+	for _, part := range strings.Split(s, ":") {
+		if !strings.HasPrefix(part, "AS") {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *policyParser) parseActions() ([]*PolicyAction, error) {
+	var lis []*PolicyAction
+
+	for {
+		t := p.peek()
+		if t.kind != tokWord || t.keyword(policyKeywords...) {
+			break
+		}
+		p.next()
+
+		a := &PolicyAction{Attr: t.text}
+		if i := strings.IndexByte(t.text, '.'); i > 0 {
+			a.Attr, a.Method = t.text[:i], t.text[i+1:]
+		}
+
+		if a.Method != "" {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			a.Args = args
+		} else {
+			op := p.next()
+			if op.kind != tokPunct || !strings.HasSuffix(op.text, "=") {
+				return nil, p.errorf(op, "expected operator after action %q, found %q", a.Attr, op.text)
+			}
+			a.Op = op.text
+
+			start, end := p.peek(), -1
+			for {
+				v := p.peek()
+				if v.kind == tokEOF || v.punct(";") || v.keyword(policyKeywords...) {
+					break
+				}
+				p.next()
+				end = v.pos + len(v.text)
+			}
+			if end < 0 {
+				return nil, p.errorf(start, "missing value for action %q", a.Attr)
+			}
+			a.Args = []string{p.src[start.pos:end]}
+		}
+		lis = append(lis, a)
+
+		if !p.peek().punct(";") {
+			break
+		}
+		p.next()
+	}
+
+	if len(lis) == 0 {
+		t := p.peek()
+		return nil, p.errorf(t, "expected action, found %q", t.text)
+	}
+
+	return lis, nil
+}
+
+// parseArgs reads a parenthesised and comma separated argument list.
+func (p *policyParser) parseArgs() ([]string, error) {
+	if t := p.next(); !t.punct("(") {
+		return nil, p.errorf(t, "expected '(', found %q", t.text)
+	}
+
+	var args []string
+	var cur []string
+	depth := 0
+	for {
+		t := p.next()
+		switch {
+		case t.kind == tokEOF:
+			return nil, p.errorf(t, "missing ')'")
+		case t.punct("(") || t.punct("{"):
+			depth++
+		case (t.punct(")") || t.punct("}")) && depth > 0:
+			depth--
+		case t.punct(")"):
+			if len(cur) > 0 {
+				args = append(args, strings.Join(cur, " "))
+			}
+			return args, nil
+		case t.punct(",") && depth == 0:
+			args = append(args, strings.Join(cur, " "))
+			cur = nil
+			continue
+		}
+		cur = append(cur, t.text)
+	}
+}
+
+// parseExpr parses OR expressions. When term is set only words accepted by
+// term are read as values.
+func (p *policyParser) parseExpr(term func(string) bool) (*PolicyExpr, error) {
+	left, err := p.parseAnd(term)
+	if err != nil {
+		return nil, err
+	}
+
+	for {
+		t := p.peek()
+		if !t.keyword("OR") && !(term != nil && t.keyword("EXCEPT")) {
+			return left, nil
+		}
+		p.next()
+
+		right, err := p.parseAnd(term)
+		if err != nil {
+			return nil, err
+		}
+		left = &PolicyExpr{Op: strings.ToUpper(t.text), Args: []*PolicyExpr{left, right}}
+	}
+}
+
+func (p *policyParser) parseAnd(term func(string) bool) (*PolicyExpr, error) {
+	left, err := p.parseNot(term)
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().keyword("AND") {
+		p.next()
+		right, err := p.parseNot(term)
+		if err != nil {
+			return nil, err
+		}
+		left = &PolicyExpr{Op: "AND", Args: []*PolicyExpr{left, right}}
+	}
+
+	return left, nil
+}
+
+func (p *policyParser) parseNot(term func(string) bool) (*PolicyExpr, error) {
+	if p.peek().keyword("NOT") {
+		p.next()
+		e, err := p.parseNot(term)
+		if err != nil {
+			return nil, err
+		}
+		return &PolicyExpr{Op: "NOT", Args: []*PolicyExpr{e}}, nil
+	}
+
+	return p.parseValue(term)
+}
+
+func (p *policyParser) parseValue(term func(string) bool) (*PolicyExpr, error) {
+	t := p.next()
+
+	switch {
+	case t.punct("("):
+		e, err := p.parseExpr(term)
+		if err != nil {
+			return nil, err
+		}
+		if c := p.next(); !c.punct(")") {
+			return nil, p.errorf(c, "expected ')', found %q", c.text)
+		}
+		return e, nil
+
+	case t.kind == tokRegex && term == nil:
+		return &PolicyExpr{Value: t.text}, nil
+
+	case t.punct("{") && term == nil:
+		var lis []string
+		for !p.peek().punct("}") {
+			v := p.next()
+			if v.kind != tokWord {
+				return nil, p.errorf(v, "expected prefix in prefix list, found %q", v.text)
+			}
+			lis = append(lis, v.text)
+			if p.peek().punct(",") {
+				p.next()
+			}
+		}
+		p.next()
+
+		value := "{" + strings.Join(lis, ", ") + "}"
+		if op := p.peek(); op.kind == tokWord && strings.HasPrefix(op.text, "^") {
+			p.next()
+			value += op.text
+		}
+		return &PolicyExpr{Value: value}, nil
+
+	case t.kind == tokWord && !t.keyword(policyKeywords...) && !t.keyword("AND", "OR", "NOT", "EXCEPT"):
+		if term != nil && !term(t.text) {
+			return nil, p.errorf(t, "expected AS expression, found %q", t.text)
+		}
+
+		if term == nil && p.peek().punct("(") {
+			args, err := p.parseArgs()
+			if err != nil {
+				return nil, err
+			}
+			return &PolicyExpr{Value: t.text + "(" + strings.Join(args, ", ") + ")"}, nil
+		}
+		if term == nil && p.peek().kind == tokPunct && strings.HasSuffix(p.peek().text, "=") {
+			op := p.next()
+			v, err := p.parseValue(nil)
+			if err != nil {
+				return nil, err
+			}
+			return &PolicyExpr{Value: t.text + " " + op.text + " " + v.Value}, nil
+		}
+
+		return &PolicyExpr{Value: t.text}, nil
+
+	default:
+		return nil, p.errorf(t, "unexpected %q in expression", t.text)
+	}
+}
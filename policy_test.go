@@ -0,0 +1,115 @@
+package rpsl_test
+
+import (
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestPolicy(t *testing.T) {
+	is := is.New(t)
+
+	dom := rpsl.ParseObject(cleanDoc(`
+        aut-num:            AS4242420000
+        import:             from AS4242420001 action pref=100; community.append(64511:1, 64511:2); accept AS-FOO AND NOT {172.20.0.0/16^+}
+        export:             to AS4242420001 announce ANY
+        mp-import:          afi ipv6.unicast from AS-ANY
+                            accept <^AS4242420001 .* AS-FOO$> OR community.contains(64511:1)
+        mp-export:          protocol BGP4 into OSPF afi ipv4, ipv6
+                            to AS1 1.2.3.4 at 5.6.7.8 action med = 10;
+                            to AS2 EXCEPT AS3 announce RS-FOO
+        mp-default:         to AS4242420001 action pref=10; networks ANY
+        `))
+
+	pol, err := dom.Get("import").Policy()
+	is.NoErr(err)
+	is.Equal(len(pol.Factors), 1)
+
+	f := pol.Factors[0]
+	is.Equal(len(f.Peerings), 1)
+	is.Equal(f.Peerings[0].AS.Value, "AS4242420001")
+	is.Equal(len(f.Peerings[0].Actions), 2)
+	is.Equal(f.Peerings[0].Actions[0], &rpsl.PolicyAction{Attr: "pref", Op: "=", Args: []string{"100"}})
+	is.Equal(f.Peerings[0].Actions[1], &rpsl.PolicyAction{Attr: "community", Method: "append", Args: []string{"64511:1", "64511:2"}})
+	is.Equal(f.Filter.Op, "AND")
+	is.Equal(f.Filter.Args[0].Value, "AS-FOO")
+	is.Equal(f.Filter.Args[1].Op, "NOT")
+	is.Equal(f.Filter.Args[1].Args[0].Value, "{172.20.0.0/16^+}")
+
+	pol, err = dom.Get("export").Policy()
+	is.NoErr(err)
+	is.Equal(pol.String(), "to AS4242420001 announce ANY")
+
+	pol, err = dom.Get("mp-import").Policy()
+	is.NoErr(err)
+	is.Equal(pol.AFI, []string{"ipv6.unicast"})
+	is.Equal(pol.Factors[0].Filter.String(), "<^AS4242420001 .* AS-FOO$> OR community.contains(64511:1)")
+
+	pol, err = dom.Get("mp-export").Policy()
+	is.NoErr(err)
+	is.Equal(pol.Protocol, "BGP4")
+	is.Equal(pol.Into, "OSPF")
+	is.Equal(pol.AFI, []string{"ipv4", "ipv6"})
+	f = pol.Factors[0]
+	is.Equal(len(f.Peerings), 2)
+	is.Equal(f.Peerings[0].Remote.Value, "1.2.3.4")
+	is.Equal(f.Peerings[0].Local.Value, "5.6.7.8")
+	is.Equal(f.Peerings[0].Actions[0].Args, []string{"10"})
+	is.Equal(f.Peerings[1].AS.Op, "EXCEPT")
+
+	pol, err = dom.Get("mp-default").Policy()
+	is.NoErr(err)
+	is.Equal(pol.Factors[0].Filter.Value, "ANY")
+
+	pol, err = rpsl.ParsePolicy("import", `from AS1 accept ANY
+        except {
+                from AS2 action community .= {64511:1}; accept AS2;
+                from AS3 accept AS3
+        } refine from AS-ANY accept NOT ANY`)
+	is.NoErr(err)
+	is.Equal(pol.Op, "except")
+	is.Equal(len(pol.Next.Factors), 2)
+	is.Equal(pol.Next.Factors[0].Peerings[0].Actions[0].Args, []string{"{64511:1}"})
+	is.Equal(pol.Next.Op, "refine")
+	is.Equal(pol.String(), "{ from AS1 accept ANY; } except { from AS2 action community .= {64511:1}; accept AS2; from AS3 accept AS3; } refine from AS-ANY accept NOT ANY")
+
+	// Policies parse again from their string form.
+	for _, attr := range dom.Attrs()[1:] {
+		pol, err := attr.Policy()
+		is.NoErr(err)
+
+		again, err := rpsl.ParsePolicy(attr.Name, pol.String())
+		is.NoErr(err)
+		is.Equal(again, pol)
+	}
+}
+
+func TestPolicyErrors(t *testing.T) {
+	is := is.New(t)
+
+	tests := []struct {
+		name, text, err string
+	}{
+		{"import", "to AS1 accept ANY", `policy: column 1: expected "from", found "to"`},
+		{"import", "from AS1 announce ANY", `policy: column 10: expected "accept", found "announce"`},
+		{"export", "to FOO announce ANY", `policy: column 4: expected AS expression, found "FOO"`},
+		{"import", "from AS1 accept (ANY", `policy: column 21: expected ')', found "end of policy"`},
+		{"import", "from AS1 action pref; accept ANY", `policy: column 21: expected operator after action "pref", found ";"`},
+		{"import", "from AS1 accept <^AS1", "policy: column 17: unterminated as-path regular expression"},
+		{"import", "from AS1 accept ANY ANY", `policy: column 21: unexpected "ANY" after policy`},
+		{"remarks", "from AS1 accept ANY", `policy: column 1: unknown policy attribute "remarks"`},
+	}
+
+	for _, tt := range tests {
+		_, err := rpsl.ParsePolicy(tt.name, tt.text)
+		is.True(err != nil)
+		if err != nil {
+			is.Equal(err.Error(), tt.err)
+		}
+	}
+
+	var missing *rpsl.Attribute
+	_, err := missing.Policy()
+	is.True(err != nil)
+}
@@ -0,0 +1,552 @@
+// Package rdap serves registry objects as RDAP (RFC 9083) responses.
+//
+// The handler answers the RFC 9082 queries
+//
+//	GET /rdap/autnum/{asn}            aut-num objects
+//	GET /rdap/ip/{address}[/{len}]    the closest inetnum or inet6num
+//	GET /rdap/domain/{name}           dns objects
+//	GET /rdap/entity/{handle}         person and role objects
+//
+// Contacts referenced by admin-c, tech-c, zone-c and abuse-c are embedded as
+// entities with a jCard built from their e-mail, phone and address.
+package rdap
+
+import (
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+// Prefix of the paths served by the handler.
+const Prefix = "/rdap/"
+
+// ContentType of RDAP responses.
+const ContentType = "application/rdap+json"
+
+var conformance = []string{"rdap_level_0"}
+
+// Common fields of RDAP object classes.
+type Common struct {
+	ObjectClassName string    `json:"objectClassName"`
+	Conformance     []string  `json:"rdapConformance,omitempty"`
+	Handle          string    `json:"handle,omitempty"`
+	Status          []string  `json:"status,omitempty"`
+	Remarks         []Notice  `json:"remarks,omitempty"`
+	Entities        []*Entity `json:"entities,omitempty"`
+}
+
+// Notice is a remark with description lines.
+type Notice struct {
+	Title       string   `json:"title,omitempty"`
+	Description []string `json:"description"`
+}
+
+// Autnum is an aut-num object.
+type Autnum struct {
+	Common
+	StartAutnum uint32 `json:"startAutnum"`
+	EndAutnum   uint32 `json:"endAutnum"`
+	Name        string `json:"name,omitempty"`
+	Country     string `json:"country,omitempty"`
+}
+
+// IPNetwork is an inetnum or inet6num object.
+type IPNetwork struct {
+	Common
+	StartAddress string `json:"startAddress"`
+	EndAddress   string `json:"endAddress"`
+	IPVersion    string `json:"ipVersion"`
+	Name         string `json:"name,omitempty"`
+	Type         string `json:"type,omitempty"`
+	Country      string `json:"country,omitempty"`
+}
+
+// Domain is a dns object.
+type Domain struct {
+	Common
+	LDHName     string        `json:"ldhName"`
+	Nameservers []*Nameserver `json:"nameservers,omitempty"`
+	SecureDNS   *SecureDNS    `json:"secureDNS,omitempty"`
+}
+
+// Nameserver of a domain with its glue addresses.
+type Nameserver struct {
+	ObjectClassName string       `json:"objectClassName"`
+	LDHName         string       `json:"ldhName"`
+	IPAddresses     *IPAddresses `json:"ipAddresses,omitempty"`
+}
+
+// IPAddresses of a nameserver.
+type IPAddresses struct {
+	V4 []string `json:"v4,omitempty"`
+	V6 []string `json:"v6,omitempty"`
+}
+
+// SecureDNS lists the DS records of a domain.
+type SecureDNS struct {
+	DelegationSigned bool     `json:"delegationSigned"`
+	DSData           []DSData `json:"dsData,omitempty"`
+}
+
+// DSData is a single DS record.
+type DSData struct {
+	KeyTag     int    `json:"keyTag"`
+	Algorithm  int    `json:"algorithm"`
+	DigestType int    `json:"digestType"`
+	Digest     string `json:"digest"`
+}
+
+// Entity is a person or role object.
+type Entity struct {
+	Common
+	Roles      []string      `json:"roles,omitempty"`
+	VCardArray []interface{} `json:"vcardArray,omitempty"`
+}
+
+// Error is an RDAP error response.
+type Error struct {
+	Conformance []string `json:"rdapConformance"`
+	ErrorCode   int      `json:"errorCode"`
+	Title       string   `json:"title"`
+	Description []string `json:"description,omitempty"`
+}
+
+// Handler serves RDAP queries from a registry.
+type Handler struct {
+	fetch rpsl.Fetcher
+
+	once    sync.Once
+	schemas *rpsl.Schemas
+	err     error
+}
+
+// New returns a handler answering queries with objects from f.
+func New(f rpsl.Fetcher) *Handler {
+	return &Handler{fetch: f}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		writeError(w, r, http.StatusMethodNotAllowed, "Method Not Allowed")
+		return
+	}
+
+	path := strings.TrimPrefix(r.URL.Path, Prefix)
+	sp := strings.SplitN(path, "/", 2)
+	if len(sp) != 2 || sp[1] == "" || !strings.HasPrefix(r.URL.Path, Prefix) {
+		writeError(w, r, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	var (
+		v   interface{}
+		err error
+	)
+	switch sp[0] {
+	case "autnum":
+		v, err = h.Autnum(sp[1])
+	case "ip":
+		v, err = h.IPNetwork(sp[1])
+	case "domain":
+		v, err = h.Domain(sp[1])
+	case "entity":
+		v, err = h.Entity(sp[1])
+	default:
+		writeError(w, r, http.StatusBadRequest, "Bad Request")
+		return
+	}
+
+	switch {
+	case err == rpsl.NotFound:
+		writeError(w, r, http.StatusNotFound, "Not Found")
+		return
+	case err == errBadRequest:
+		writeError(w, r, http.StatusBadRequest, "Bad Request")
+		return
+	case err != nil:
+		writeError(w, r, http.StatusInternalServerError, "Internal Server Error", err.Error())
+		return
+	}
+
+	writeJSON(w, r, http.StatusOK, v)
+}
+
+var errBadRequest = errors.New("bad request")
+
+// Autnum looks up an aut-num by number with or without the AS prefix.
+func (h *Handler) Autnum(query string) (*Autnum, error) {
+	asn, err := strconv.ParseUint(strings.TrimPrefix(strings.ToUpper(query), "AS"), 10, 32)
+	if err != nil {
+		return nil, errBadRequest
+	}
+
+	dom, err := h.fetch.LoadObject("aut-num", "AS"+strconv.FormatUint(asn, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	common, err := h.common("autnum", dom)
+	if err != nil {
+		return nil, err
+	}
+
+	a := &Autnum{
+		Common:      common,
+		StartAutnum: uint32(asn),
+		EndAutnum:   uint32(asn),
+		Name:        dom.Get("as-name").Text(),
+		Country:     dom.Get("country").Text(),
+	}
+	a.Conformance = conformance
+
+	return a, nil
+}
+
+// IPNetwork looks up the closest inetnum or inet6num containing an address
+// or prefix.
+func (h *Handler) IPNetwork(query string) (*IPNetwork, error) {
+	ipnet, err := parseNetwork(query)
+	if err != nil {
+		return nil, errBadRequest
+	}
+	ones, bits := ipnet.Mask.Size()
+
+	schema, version := "inetnum", "v4"
+	if bits == 8*net.IPv6len {
+		schema, version = "inet6num", "v6"
+	}
+
+	for n := ones; n >= 0; n-- {
+		mask := net.CIDRMask(n, bits)
+		cidr := &net.IPNet{IP: ipnet.IP.Mask(mask), Mask: mask}
+
+		dom, err := h.fetch.LoadObject(schema, cidr.String())
+		if err == rpsl.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		end := make(net.IP, len(cidr.IP))
+		for i := range end {
+			end[i] = cidr.IP[i] | ^mask[i]
+		}
+
+		common, err := h.common("ip network", dom)
+		if err != nil {
+			return nil, err
+		}
+
+		ip := &IPNetwork{
+			Common:       common,
+			StartAddress: cidr.IP.String(),
+			EndAddress:   end.String(),
+			IPVersion:    version,
+			Name:         dom.Get("netname").Text(),
+			Type:         dom.Get("status").Text(),
+			Country:      dom.Get("country").Text(),
+		}
+		ip.Handle = cidr.String()
+		ip.Conformance = conformance
+
+		return ip, nil
+	}
+
+	return nil, rpsl.NotFound
+}
+
+func parseNetwork(query string) (*net.IPNet, error) {
+	if strings.Contains(query, "/") {
+		_, ipnet, err := net.ParseCIDR(query)
+		return ipnet, err
+	}
+
+	ip := net.ParseIP(query)
+	if ip == nil {
+		return nil, errBadRequest
+	}
+	if ip4 := ip.To4(); ip4 != nil {
+		return &net.IPNet{IP: ip4, Mask: net.CIDRMask(32, 32)}, nil
+	}
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
+
+// Domain looks up a dns object by name.
+func (h *Handler) Domain(query string) (*Domain, error) {
+	name := strings.ToLower(strings.TrimSuffix(query, "."))
+
+	dom, err := h.fetch.LoadObject("dns", name)
+	if err != nil {
+		return nil, err
+	}
+
+	common, err := h.common("domain", dom)
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Domain{Common: common, LDHName: name}
+	d.Conformance = conformance
+
+	for _, attr := range dom.GetAll("nserver") {
+		fields := attr.Fields()
+		if len(fields) == 0 {
+			continue
+		}
+
+		ns := &Nameserver{ObjectClassName: "nameserver", LDHName: strings.TrimSuffix(fields[0], ".")}
+		for _, addr := range fields[1:] {
+			ip := net.ParseIP(addr)
+			switch {
+			case ip == nil:
+				continue
+			case ns.IPAddresses == nil:
+				ns.IPAddresses = &IPAddresses{}
+			}
+			if ip.To4() != nil {
+				ns.IPAddresses.V4 = append(ns.IPAddresses.V4, addr)
+			} else {
+				ns.IPAddresses.V6 = append(ns.IPAddresses.V6, addr)
+			}
+		}
+		d.Nameservers = append(d.Nameservers, ns)
+	}
+
+	for _, attr := range dom.GetAll("ds-rdata") {
+		fields := attr.Fields()
+		if len(fields) < 4 {
+			continue
+		}
+		keyTag, err1 := strconv.Atoi(fields[0])
+		alg, err2 := strconv.Atoi(fields[1])
+		digestType, err3 := strconv.Atoi(fields[2])
+		if err1 != nil || err2 != nil || err3 != nil {
+			continue
+		}
+
+		if d.SecureDNS == nil {
+			d.SecureDNS = &SecureDNS{DelegationSigned: true}
+		}
+		d.SecureDNS.DSData = append(d.SecureDNS.DSData, DSData{
+			KeyTag:     keyTag,
+			Algorithm:  alg,
+			DigestType: digestType,
+			Digest:     strings.Join(fields[3:], ""),
+		})
+	}
+
+	return d, nil
+}
+
+// Entity looks up a person or role by nic-hdl.
+func (h *Handler) Entity(handle string) (*Entity, error) {
+	for _, schema := range []string{"person", "role"} {
+		dom, err := h.fetch.LoadObject(schema, handle)
+		if err == rpsl.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		e := h.entity(dom)
+		e.Conformance = conformance
+		e.Entities, err = h.contacts(dom)
+		if err != nil {
+			return nil, err
+		}
+
+		return e, nil
+	}
+
+	return nil, rpsl.NotFound
+}
+
+func (h *Handler) entity(dom *rpsl.Object) *Entity {
+	return &Entity{
+		Common:     Common{ObjectClassName: "entity", Handle: dom.Name(), Remarks: remarks(dom)},
+		VCardArray: vcard(dom),
+	}
+}
+
+func (h *Handler) common(class string, dom *rpsl.Object) (Common, error) {
+	contacts, err := h.contacts(dom)
+	if err != nil {
+		return Common{}, err
+	}
+
+	return Common{
+		ObjectClassName: class,
+		Handle:          dom.Name(),
+		Status:          []string{"active"},
+		Remarks:         remarks(dom),
+		Entities:        contacts,
+	}, nil
+}
+
+var contactRoles = []struct{ key, role string }{
+	{"admin-c", "administrative"},
+	{"tech-c", "technical"},
+	{"zone-c", "noc"},
+	{"abuse-c", "abuse"},
+}
+
+// contacts follows the contact lookups of dom and returns them as entities.
+// A contact referenced by several keys is listed once with each role.
+// Contacts that are not found are left out.
+func (h *Handler) contacts(dom *rpsl.Object) ([]*Entity, error) {
+	var lis []*Entity
+	seen := make(map[string]*Entity)
+
+	for _, c := range contactRoles {
+		for _, attr := range dom.GetAll(c.key) {
+			contact, err := h.lookup(attr)
+			if err == rpsl.NotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			e, ok := seen[contact.Name()]
+			if !ok {
+				e = h.entity(contact)
+				seen[contact.Name()] = e
+				lis = append(lis, e)
+			}
+			e.Roles = append(e.Roles, c.role)
+		}
+	}
+
+	return lis, nil
+}
+
+// lookup loads the object referenced by a lookup attribute trying each schema
+// choice of its spec, or person and role when there is none.
+func (h *Handler) lookup(attr *rpsl.Attribute) (*rpsl.Object, error) {
+	fields := attr.Fields()
+	if len(fields) == 0 {
+		return nil, rpsl.NotFound
+	}
+
+	choices := []string{"person", "role"}
+	args := attr.Args()
+	for _, name := range args.Keys() {
+		if arg, ok := args.Get(name).(*rpsl.LookupArg); ok {
+			choices = arg.Choices
+			break
+		}
+	}
+
+	schemas, err := h.loadSchemas()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, choice := range choices {
+		lookups := []string{choice}
+		if schemas != nil {
+			lookups = schemas.Lookups(choice)
+		}
+
+		for _, schema := range lookups {
+			dom, err := h.fetch.LoadObject(schema, fields[0])
+			if err == rpsl.NotFound {
+				continue
+			}
+			return dom, err
+		}
+	}
+
+	return nil, rpsl.NotFound
+}
+
+// loadSchemas returns the schemas of the registry, or nil when they can't be
+// listed. Registries with a Schemas method, such as *rpsl.RPSL, are asked on
+// every call so that reloads are followed. Otherwise the schemas are listed
+// once on first use.
+func (h *Handler) loadSchemas() (*rpsl.Schemas, error) {
+	if s, ok := h.fetch.(interface{ Schemas() (*rpsl.Schemas, error) }); ok {
+		return s.Schemas()
+	}
+
+	h.once.Do(func() {
+		list, ok := h.fetch.(rpsl.Lister)
+		if !ok {
+			return
+		}
+		var lis rpsl.ListObject
+		if lis, h.err = list.ListObjects("schema"); h.err != nil {
+			return
+		}
+		h.schemas, h.err = rpsl.ParseSchemas(lis)
+	})
+
+	return h.schemas, h.err
+}
+
+func remarks(dom *rpsl.Object) []Notice {
+	var lis []Notice
+	for _, key := range []string{"descr", "remarks"} {
+		for _, attr := range dom.GetAll(key) {
+			if lines := attr.Lines(); len(lines) > 0 {
+				lis = append(lis, Notice{Title: key, Description: lines})
+			}
+		}
+	}
+	return lis
+}
+
+// vcard builds a jCard (RFC 7095) for a person or role.
+func vcard(dom *rpsl.Object) []interface{} {
+	props := []interface{}{
+		[]interface{}{"version", map[string]string{}, "text", "4.0"},
+		[]interface{}{"fn", map[string]string{}, "text", dom.Get(dom.Schema()).Text()},
+	}
+	if dom.Schema() == "role" {
+		props = append(props, []interface{}{"kind", map[string]string{}, "text", "group"})
+	}
+
+	for _, attr := range dom.GetAll("e-mail") {
+		props = append(props, []interface{}{"email", map[string]string{}, "text", attr.Text()})
+	}
+	for _, attr := range dom.GetAll("abuse-mailbox") {
+		props = append(props, []interface{}{"email", map[string]string{"type": "abuse"}, "text", attr.Text()})
+	}
+	for _, attr := range dom.GetAll("phone") {
+		props = append(props, []interface{}{"tel", map[string]string{"type": "voice"}, "uri", "tel:" + strings.Join(attr.Fields(), "")})
+	}
+	for _, attr := range dom.GetAll("address") {
+		label := strings.Join(attr.Lines(), "\n")
+		props = append(props, []interface{}{"adr", map[string]string{"label": label}, "text", []string{"", "", "", "", "", "", ""}})
+	}
+
+	return []interface{}{"vcard", props}
+}
+
+func writeJSON(w http.ResponseWriter, r *http.Request, code int, v interface{}) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		code = http.StatusInternalServerError
+		body, _ = json.Marshal(&Error{Conformance: conformance, ErrorCode: code, Title: "Internal Server Error"})
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(code)
+	if r.Method != http.MethodHead {
+		w.Write(body)
+	}
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, code int, title string, description ...string) {
+	writeJSON(w, r, code, &Error{Conformance: conformance, ErrorCode: code, Title: title, Description: description})
+}
@@ -0,0 +1,166 @@
+package rdap_test
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"testing/fstest"
+
+	"github.com/matryer/is"
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/rdap"
+)
+
+var registry = fstest.MapFS{
+	"schema/PERSON-SCHEMA": {Data: []byte(`
+schema:  PERSON-SCHEMA
+key:     person   required single
+key:     nic-hdl  required single primary
+`)},
+	"schema/ROLE-SCHEMA": {Data: []byte(`
+schema:  ROLE-SCHEMA
+key:     role     required single
+key:     nic-hdl  required single primary
+`)},
+	"schema/AUT-NUM-SCHEMA": {Data: []byte(`
+schema:  AUT-NUM-SCHEMA
+key:     aut-num  required single primary
+key:     admin-c  optional multiple > [lookup:person,role]
+key:     tech-c   optional multiple > [lookup:nic-hdl]
+`)},
+	"schema/INETNUM-SCHEMA": {Data: []byte(`
+schema:  INETNUM-SCHEMA
+key:     inetnum  required single
+key:     cidr     required single primary
+`)},
+	"person/XUU-DN42": {Data: []byte(`
+person:   Xuu
+e-mail:   xuu@dn42.us
+phone:    +1 555 0100
+address:  1 Main St
+          Springfield
+nic-hdl:  XUU-DN42
+`)},
+	"role/SOURIS-DN42": {Data: []byte(`
+role:           Souris Organization Role
+abuse-mailbox:  abuse@sour.is
+nic-hdl:        SOURIS-DN42
+`)},
+	"aut-num/AS4242420000": {Data: []byte(`
+aut-num:  AS4242420000
+as-name:  XUU-AS
+descr:    Xuu network
+admin-c:  XUU-DN42
+tech-c:   XUU-DN42
+tech-c:   SOURIS-DN42
+tech-c:   MISSING-DN42
+`)},
+	"inetnum/172.21.64.0_29": {Data: []byte(`
+inetnum:  172.21.64.0 - 172.21.64.7
+cidr:     172.21.64.0/29
+netname:  XUU-TEST-NET
+status:   ALLOCATED
+`)},
+	"dns/xuu.dn42": {Data: []byte(`
+dns:       xuu.dn42
+nserver:   ns1.xuu.dn42 172.21.64.1
+nserver:   ns2.xuu.dn42 fd00::1
+nserver:   ns.example.com
+ds-rdata:  12345 13 2 ABCDEF 0123
+`)},
+}
+
+func TestHandler(t *testing.T) {
+	is := is.New(t)
+
+	h := rdap.New(rpsl.NewRPSL(rpsl.WithFS(registry)))
+	get := func(path string, v interface{}) int {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+		is.Equal(w.Header().Get("Content-Type"), "application/rdap+json")
+		is.NoErr(json.Unmarshal(w.Body.Bytes(), v))
+		return w.Code
+	}
+
+	var autnum rdap.Autnum
+	is.Equal(get("/rdap/autnum/4242420000", &autnum), http.StatusOK)
+	is.Equal(autnum.ObjectClassName, "autnum")
+	is.Equal(autnum.Conformance, []string{"rdap_level_0"})
+	is.Equal(autnum.StartAutnum, uint32(4242420000))
+	is.Equal(autnum.Name, "XUU-AS")
+	is.Equal(autnum.Remarks[0].Description, []string{"Xuu network"})
+	is.Equal(len(autnum.Entities), 2)
+	is.Equal(autnum.Entities[0].Handle, "XUU-DN42")
+	is.Equal(autnum.Entities[0].Roles, []string{"administrative", "technical"})
+	is.Equal(autnum.Entities[1].Handle, "SOURIS-DN42")
+
+	vcard, _ := json.Marshal(autnum.Entities[0].VCardArray)
+	is.Equal(string(vcard), `["vcard",[["version",{},"text","4.0"],["fn",{},"text","Xuu"],["email",{},"text","xuu@dn42.us"],["tel",{"type":"voice"},"uri","tel:+15550100"],["adr",{"label":"1 Main St\nSpringfield"},"text",["","","","","","",""]]]]`)
+
+	var ip rdap.IPNetwork
+	is.Equal(get("/rdap/ip/172.21.64.5", &ip), http.StatusOK)
+	is.Equal(ip.Handle, "172.21.64.0/29")
+	is.Equal(ip.StartAddress, "172.21.64.0")
+	is.Equal(ip.EndAddress, "172.21.64.7")
+	is.Equal(ip.IPVersion, "v4")
+	is.Equal(ip.Name, "XUU-TEST-NET")
+
+	var domain rdap.Domain
+	is.Equal(get("/rdap/domain/XUU.dn42.", &domain), http.StatusOK)
+	is.Equal(domain.LDHName, "xuu.dn42")
+	is.Equal(len(domain.Nameservers), 3)
+	is.Equal(domain.Nameservers[0].IPAddresses.V4, []string{"172.21.64.1"})
+	is.Equal(domain.Nameservers[1].IPAddresses.V6, []string{"fd00::1"})
+	is.True(domain.Nameservers[2].IPAddresses == nil)
+	is.Equal(domain.SecureDNS.DSData, []rdap.DSData{{KeyTag: 12345, Algorithm: 13, DigestType: 2, Digest: "ABCDEF0123"}})
+
+	var entity rdap.Entity
+	is.Equal(get("/rdap/entity/SOURIS-DN42", &entity), http.StatusOK)
+	is.Equal(entity.ObjectClassName, "entity")
+	vcard, _ = json.Marshal(entity.VCardArray)
+	is.Equal(string(vcard), `["vcard",[["version",{},"text","4.0"],["fn",{},"text","Souris Organization Role"],["kind",{},"text","group"],["email",{"type":"abuse"},"text","abuse@sour.is"]]]`)
+
+	var e rdap.Error
+	is.Equal(get("/rdap/ip/10.0.0.1", &e), http.StatusNotFound)
+	is.Equal(e.ErrorCode, 404)
+	is.Equal(e.Title, "Not Found")
+	is.Equal(get("/rdap/autnum/foo", &e), http.StatusBadRequest)
+	is.Equal(get("/rdap/nameserver/ns1.xuu.dn42", &e), http.StatusBadRequest)
+}
+
+// failFetcher fails loading objects of one schema.
+type failFetcher struct {
+	*rpsl.RPSL
+	schema string
+}
+
+func (f failFetcher) LoadObject(schema, name string) (*rpsl.Object, error) {
+	if schema == f.schema {
+		return nil, errors.New("backend unavailable")
+	}
+	return f.RPSL.LoadObject(schema, name)
+}
+
+func TestHandlerErrors(t *testing.T) {
+	is := is.New(t)
+
+	// Failing to load a contact is a server error rather than not found.
+	h := rdap.New(failFetcher{rpsl.NewRPSL(rpsl.WithFS(registry)), "person"})
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/rdap/autnum/AS4242420000", nil))
+	is.Equal(w.Code, http.StatusInternalServerError)
+
+	var e rdap.Error
+	is.NoErr(json.Unmarshal(w.Body.Bytes(), &e))
+	is.Equal(e.Description, []string{"backend unavailable"})
+
+	// HEAD requests have no body.
+	h = rdap.New(rpsl.NewRPSL(rpsl.WithFS(registry)))
+	w = httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("HEAD", "/rdap/autnum/AS4242420000", nil))
+	is.Equal(w.Code, http.StatusOK)
+	is.True(w.Header().Get("Content-Length") != "0")
+	is.Equal(w.Body.Len(), 0)
+}
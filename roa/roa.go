@@ -0,0 +1,235 @@
+// Package roa generates route origin authorisation tables from route and
+// route6 objects.
+package roa
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+// ROA authorises an origin ASN to announce a prefix up to max length.
+type ROA struct {
+	Prefix    *net.IPNet
+	MaxLength int
+	ASN       uint32
+}
+
+// IsIPv4 reports whether the prefix is an IPv4 network.
+func (r ROA) IsIPv4() bool {
+	return r.Prefix.IP.To4() != nil
+}
+
+func (r ROA) String() string {
+	return fmt.Sprintf("%s max %d as %d", r.Prefix, r.MaxLength, r.ASN)
+}
+
+// Table is a list of ROAs.
+type Table []ROA
+
+// FromObjects builds a sorted table from route and route6 objects. Other
+// objects are skipped. Each origin of a route produces one ROA. Routes that
+// fail to parse are left out of the table and their errors returned together
+// with it.
+func FromObjects(lis rpsl.ListObject) (Table, error) {
+	var (
+		t    Table
+		errs errorList
+	)
+	for _, dom := range lis {
+		switch dom.Schema() {
+		case "route", "route6":
+		default:
+			continue
+		}
+
+		roas, err := FromRoute(dom)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		t = append(t, roas...)
+	}
+
+	t.Sort()
+	t = t.unique()
+
+	if len(errs) > 0 {
+		return t, errs
+	}
+	return t, nil
+}
+
+type errorList []error
+
+func (lis errorList) Error() string {
+	s := make([]string, len(lis))
+	for i, err := range lis {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+// FromRoute parses the ROAs for a single route or route6 object.
+// When max-length is missing the prefix length is used.
+func FromRoute(dom *rpsl.Object) (Table, error) {
+	_, prefix, err := net.ParseCIDR(dom.Get(dom.Schema()).Text())
+	if err != nil {
+		return nil, fmt.Errorf("route %s: %w", dom.Name(), err)
+	}
+	ones, bits := prefix.Mask.Size()
+
+	maxLength := ones
+	if attr := dom.Get("max-length"); attr != nil && attr.Text() != "" {
+		maxLength, err = strconv.Atoi(attr.Text())
+		if err != nil {
+			return nil, fmt.Errorf("route %s: max-length: %w", dom.Name(), err)
+		}
+		if maxLength < ones || maxLength > bits {
+			return nil, fmt.Errorf("route %s: max-length %d out of range %d-%d", dom.Name(), maxLength, ones, bits)
+		}
+	}
+
+	origins := dom.GetAll("origin").Fields()
+	if len(origins) == 0 {
+		return nil, fmt.Errorf("route %s: missing origin", dom.Name())
+	}
+
+	t := make(Table, len(origins))
+	for i, origin := range origins {
+		asn, err := ParseASN(origin)
+		if err != nil {
+			return nil, fmt.Errorf("route %s: %w", dom.Name(), err)
+		}
+		t[i] = ROA{Prefix: prefix, MaxLength: maxLength, ASN: asn}
+	}
+
+	return t, nil
+}
+
+// ParseASN parses an AS number in the form AS4242420000.
+func ParseASN(s string) (uint32, error) {
+	if len(s) < 3 || !strings.EqualFold(s[:2], "AS") {
+		return 0, fmt.Errorf("invalid origin %q", s)
+	}
+
+	asn, err := strconv.ParseUint(s[2:], 10, 32)
+	if err != nil {
+		return 0, fmt.Errorf("invalid origin %q", s)
+	}
+
+	return uint32(asn), nil
+}
+
+// Sort the table with IPv4 before IPv6, then by address, prefix length,
+// max length and ASN.
+func (t Table) Sort() {
+	sort.Slice(t, func(i, j int) bool { return t[i].less(t[j]) })
+}
+
+func (r ROA) less(o ROA) bool {
+	if r.IsIPv4() != o.IsIPv4() {
+		return r.IsIPv4()
+	}
+	if c := bytes.Compare(r.Prefix.IP.To16(), o.Prefix.IP.To16()); c != 0 {
+		return c < 0
+	}
+	rl, _ := r.Prefix.Mask.Size()
+	ol, _ := o.Prefix.Mask.Size()
+	if rl != ol {
+		return rl < ol
+	}
+	if r.MaxLength != o.MaxLength {
+		return r.MaxLength < o.MaxLength
+	}
+	return r.ASN < o.ASN
+}
+
+func (t Table) unique() Table {
+	if len(t) == 0 {
+		return t
+	}
+
+	lis := t[:1]
+	for _, r := range t[1:] {
+		if lis[len(lis)-1].less(r) {
+			lis = append(lis, r)
+		}
+	}
+	return lis
+}
+
+// IPv4 returns the IPv4 entries of the table.
+func (t Table) IPv4() Table {
+	return t.filter(true)
+}
+
+// IPv6 returns the IPv6 entries of the table.
+func (t Table) IPv6() Table {
+	return t.filter(false)
+}
+
+func (t Table) filter(v4 bool) Table {
+	var lis Table
+	for _, r := range t {
+		if r.IsIPv4() == v4 {
+			lis = append(lis, r)
+		}
+	}
+	return lis
+}
+
+// WriteBird writes the table as bird2 static roa routes.
+func (t Table) WriteBird(w io.Writer) error {
+	for _, r := range t {
+		if _, err := fmt.Fprintf(w, "route %s max %d as %d;\n", r.Prefix, r.MaxLength, r.ASN); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteOpenBGPD writes the table as an OpenBGPD roa-set.
+func (t Table) WriteOpenBGPD(w io.Writer) error {
+	if _, err := io.WriteString(w, "roa-set {\n"); err != nil {
+		return err
+	}
+	for _, r := range t {
+		if _, err := fmt.Fprintf(w, "\t%s maxlen %d source-as %d\n", r.Prefix, r.MaxLength, r.ASN); err != nil {
+			return err
+		}
+	}
+	_, err := io.WriteString(w, "}\n")
+	return err
+}
+
+type jsonROA struct {
+	Prefix    string `json:"prefix"`
+	MaxLength int    `json:"maxLength"`
+	ASN       string `json:"asn"`
+}
+
+// WriteJSON writes the table in the RPKI-to-router JSON format.
+func (t Table) WriteJSON(w io.Writer) error {
+	lis := make([]jsonROA, len(t))
+	for i, r := range t {
+		lis[i] = jsonROA{
+			Prefix:    r.Prefix.String(),
+			MaxLength: r.MaxLength,
+			ASN:       "AS" + strconv.FormatUint(uint64(r.ASN), 10),
+		}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(struct {
+		ROAs []jsonROA `json:"roas"`
+	}{lis})
+}
@@ -0,0 +1,105 @@
+package roa_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/roa"
+)
+
+const txtRoutes = `route6:             fd42:4242:2601::/48
+origin:             AS4242420001
+max-length:         64
+mnt-by:             XUU-MNT
+source:             DN42
+
+route:              172.21.64.0/29
+origin:             AS4242420001
+origin:             AS4242420000
+mnt-by:             XUU-MNT
+source:             DN42
+
+route:              172.20.0.0/24
+origin:             AS4242420000
+max-length:         28
+mnt-by:             DN42-MNT
+source:             DN42
+
+route:              172.20.0.0/24
+origin:             AS4242420000
+max-length:         28
+mnt-by:             DN42-MNT
+source:             DN42
+
+mntner:             XUU-MNT
+source:             DN42
+`
+
+func TestTable(t *testing.T) {
+	is := is.New(t)
+
+	tbl, err := roa.FromObjects(rpsl.ParseAll(strings.NewReader(txtRoutes)))
+	is.NoErr(err)
+	is.Equal(len(tbl), 4)
+	is.Equal(len(tbl.IPv4()), 3)
+	is.Equal(len(tbl.IPv6()), 1)
+
+	var b strings.Builder
+	is.NoErr(tbl.WriteBird(&b))
+	is.Equal(b.String(), `route 172.20.0.0/24 max 28 as 4242420000;
+route 172.21.64.0/29 max 29 as 4242420000;
+route 172.21.64.0/29 max 29 as 4242420001;
+route fd42:4242:2601::/48 max 64 as 4242420001;
+`)
+
+	b.Reset()
+	is.NoErr(tbl.IPv6().WriteOpenBGPD(&b))
+	is.Equal(b.String(), `roa-set {
+	fd42:4242:2601::/48 maxlen 64 source-as 4242420001
+}
+`)
+
+	b.Reset()
+	is.NoErr(tbl[:1].WriteJSON(&b))
+	is.Equal(b.String(), `{
+  "roas": [
+    {
+      "prefix": "172.20.0.0/24",
+      "maxLength": 28,
+      "asn": "AS4242420000"
+    }
+  ]
+}
+`)
+}
+
+func TestFromRouteErrors(t *testing.T) {
+	is := is.New(t)
+
+	tests := []string{
+		"route: 172.20.0.0\norigin: AS1",
+		"route: 172.20.0.0/24",
+		"route: 172.20.0.0/24\norigin: 4242420000",
+		"route: 172.20.0.0/24\norigin: AS1\nmax-length: 16",
+		"route: 172.20.0.0/24\norigin: AS1\nmax-length: xx",
+	}
+
+	for _, tt := range tests {
+		_, err := roa.FromRoute(rpsl.ParseObject(tt))
+		is.True(err != nil)
+	}
+
+	// A bad route is skipped and reported without losing the others.
+	lis := rpsl.ParseAll(strings.NewReader(txtRoutes))
+	lis = append(lis, rpsl.ParseObject(tests[2]), rpsl.ParseObject(tests[4]))
+	tbl, err := roa.FromObjects(lis)
+	is.True(err != nil)
+	is.Equal(strings.Count(err.Error(), "\n"), 1)
+	is.Equal(len(tbl), 4)
+
+	asn, err := roa.ParseASN("as64512")
+	is.NoErr(err)
+	is.Equal(asn, uint32(64512))
+}
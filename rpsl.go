@@ -10,15 +10,19 @@ import (
 	"sort"
 	"strconv"
 	"strings"
-	"unicode/utf8"
+	"sync"
 )
 
-// RPSL manages loading, indexing, and parsing rpsl objects.
+// RPSL manages loading, indexing, and parsing rpsl objects. It is safe for
+// concurrent use when its fetcher is, and Reload may be called while other
+// goroutines read from it.
 type RPSL struct {
 	Schema map[string]*Schema
 
+	mu    sync.RWMutex
 	index Indexer
 	fetch Fetcher
+	list  Lister
 }
 
 // NewRPSL create a new RPSL
@@ -41,9 +45,37 @@ func NewRPSL(opts ...Option) *RPSL {
 		rpsl.index = &nullFS{}
 	}
 
+	if rpsl.list == nil {
+		rpsl.list = &nullFS{}
+	}
+
 	return rpsl
 }
 
+// Reload replaces the fetcher, indexer and lister of r with those configured
+// by opts, as NewRPSL does. Calls in progress complete against the previous
+// ones.
+func (r *RPSL) Reload(opts ...Option) {
+	next := NewRPSL(opts...)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.fetch, r.index, r.list = next.fetch, next.index, next.list
+}
+
+// backends returns the current fetcher, indexer and lister.
+func (r *RPSL) backends() (Fetcher, Indexer, Lister) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.fetch, r.index, r.list
+}
+
+var _ Fetcher = (*RPSL)(nil)
+var _ Indexer = (*RPSL)(nil)
+var _ Lister = (*RPSL)(nil)
+
 type Option interface {
 	Apply(*RPSL)
 }
@@ -63,6 +95,10 @@ type Object struct {
 	attributes ListAttribute
 	keys       map[string][]int
 	schema     *Schema
+
+	// shared is set when attributes and keys belong to a snapshot. They are
+	// copied before the first change.
+	shared bool
 }
 
 // ParseObject parses an object from string and returns it.
@@ -106,9 +142,6 @@ func (dom *Object) String() string {
 	}
 	var lis []string
 	for _, attr := range dom.attributes {
-		if attr == nil {
-			continue
-		}
 		lis = append(lis, attr.StringN(padLen))
 	}
 
@@ -151,48 +184,90 @@ func (dom *Object) Set(name string, values ...string) {
 // SetN writes to Nth attribute matching name. Values are parsed with comments.
 // if index == -1 or index > len(GetAll(name)) the attribute is appended to end.
 func (dom *Object) SetN(name string, index int, values ...string) {
-	var rows []Value
-	switch len(values) {
-	case 0:
-	case 1:
-		rows = append(rows, NewValue(values[0]))
-	default:
-		rows = make([]Value, len(values))
-		for i, v := range values {
-			rows[i] = NewValue(v)
-		}
+	key, ok := dom.keys[name]
+	if !ok {
+		return
 	}
 
-	if key, ok := dom.keys[name]; ok {
-		if index > -1 && len(key) > index {
-			dom.attributes[key[index]].rows = rows
-		} else {
-			dom.keys[name] = append(key, len(dom.attributes))
-			dom.attributes = append(dom.attributes, &Attribute{Name: name, rows: rows})
-		}
+	if index > -1 && len(key) > index {
+		dom.own()
+		dom.attributes[key[index]] = &Attribute{Name: name, rows: newRows(values)}
+		return
 	}
+	dom.Add(name, values...)
 }
 
 // Add appends attribute to end of object. Values are parsed with comments.
 func (dom *Object) Add(name string, values ...string) {
-	var rows []Value
-	switch len(values) {
-	case 0:
-	case 1:
-		rows = append(rows, NewValue(values[0]))
-	default:
-		rows = make([]Value, len(values))
-		for i, v := range values {
-			rows[i] = NewValue(v)
-		}
+	dom.InsertBefore(len(dom.attributes), name, values...)
+}
+
+// InsertBefore adds an attribute before the attribute at index. An index past
+// the end appends the attribute. Values are parsed with comments.
+func (dom *Object) InsertBefore(index int, name string, values ...string) {
+	if index < 0 {
+		index = 0
+	}
+	if index > len(dom.attributes) {
+		index = len(dom.attributes)
 	}
 
-	if key, ok := dom.keys[name]; ok {
-		dom.keys[name] = append(key, len(dom.attributes))
+	dom.own()
+	dom.attributes = append(dom.attributes, nil)
+	copy(dom.attributes[index+1:], dom.attributes[index:])
+	dom.attributes[index] = &Attribute{Name: name, rows: newRows(values)}
+	dom.reindex()
+}
+
+// InsertAfter adds an attribute after the attribute at index. Values are
+// parsed with comments.
+func (dom *Object) InsertAfter(index int, name string, values ...string) {
+	dom.InsertBefore(index+1, name, values...)
+}
+
+// Move the attribute at index from to index to, shifting the attributes
+// between. Indexes out of range are ignored.
+func (dom *Object) Move(from, to int) {
+	n := len(dom.attributes)
+	if from < 0 || from >= n || to < 0 || to >= n || from == to {
+		return
+	}
+
+	dom.own()
+	attr := dom.attributes[from]
+	if from < to {
+		copy(dom.attributes[from:], dom.attributes[from+1:to+1])
 	} else {
-		dom.keys[name] = []int{len(dom.attributes)}
+		copy(dom.attributes[to+1:], dom.attributes[to:from])
 	}
-	dom.attributes = append(dom.attributes, &Attribute{Name: name, rows: rows})
+	dom.attributes[to] = attr
+	dom.reindex()
+}
+
+// Normalize sorts the attributes into the order the keys are declared by the
+// applied schema. Repeated keys keep their order and keys not in the schema
+// are moved to the end. Objects without a schema are unchanged.
+func (dom *Object) Normalize() {
+	if dom.schema == nil {
+		return
+	}
+
+	rank := make(map[string]int, len(dom.schema.Keys))
+	for i, key := range dom.schema.Keys {
+		rank[key] = i
+	}
+	order := func(name string) int {
+		if i, ok := rank[name]; ok {
+			return i
+		}
+		return len(rank)
+	}
+
+	dom.own()
+	sort.SliceStable(dom.attributes, func(i, j int) bool {
+		return order(dom.attributes[i].Name) < order(dom.attributes[j].Name)
+	})
+	dom.reindex()
 }
 
 // Delete the first attribute that matches name.
@@ -202,29 +277,86 @@ func (dom *Object) Delete(name string) {
 
 // DeleteN the Nth attribute that matches name.
 func (dom *Object) DeleteN(name string, index int) {
-	if k, ok := dom.keys[name]; ok {
-		if len(k) > index {
-			dom.attributes[k[index]] = nil
-			k = append(k[:index], k[index+1:]...)
-			dom.keys[name] = k
-		}
-
-		if len(k) == 0 {
-			delete(dom.keys, name)
-		}
+	k, ok := dom.keys[name]
+	if !ok || index < 0 || index >= len(k) {
+		return
 	}
+
+	dom.own()
+	i := k[index]
+	dom.attributes = append(dom.attributes[:i], dom.attributes[i+1:]...)
+	dom.reindex()
 }
 
 // DeleteAll the attributes that match name.
 func (dom *Object) DeleteAll(name string, index int) {
-	if k, ok := dom.keys[name]; ok {
-		for _, i := range k {
-			dom.attributes[i] = nil
+	if _, ok := dom.keys[name]; !ok {
+		return
+	}
+
+	dom.own()
+	lis := dom.attributes[:0]
+	for _, attr := range dom.attributes {
+		if attr.Name != name {
+			lis = append(lis, attr)
 		}
-		delete(dom.keys, name)
+	}
+	dom.attributes = lis
+	dom.reindex()
+}
+
+// reindex rebuilds the positions of attributes by name after the order of
+// attributes changes.
+func (dom *Object) reindex() {
+	dom.keys = make(map[string][]int, len(dom.keys))
+	for i, attr := range dom.attributes {
+		dom.keys[attr.Name] = append(dom.keys[attr.Name], i)
 	}
 }
 
+// newRows parses values with comments.
+func newRows(values []string) []Value {
+	if len(values) == 0 {
+		return nil
+	}
+
+	rows := make([]Value, len(values))
+	for i, v := range values {
+		rows[i] = NewValue(v)
+	}
+	return rows
+}
+
+// Clone returns a copy of the object that can be changed independently.
+func (dom *Object) Clone() *Object {
+	c := dom.view()
+	c.own()
+	return c
+}
+
+// view returns a copy of the object sharing its attributes until changed.
+// Attributes are never modified in place so sharing them is safe.
+func (dom *Object) view() *Object {
+	v := *dom
+	v.shared = true
+	return &v
+}
+
+// own copies shared attributes and keys before they are changed.
+func (dom *Object) own() {
+	if !dom.shared {
+		return
+	}
+
+	dom.attributes = append(ListAttribute(nil), dom.attributes...)
+	keys := make(map[string][]int, len(dom.keys))
+	for name, k := range dom.keys {
+		keys[name] = append([]int(nil), k...)
+	}
+	dom.keys = keys
+	dom.shared = false
+}
+
 // Schema name for object
 func (dom *Object) Schema() string {
 	if dom == nil || len(dom.attributes) == 0 {
@@ -235,7 +367,7 @@ func (dom *Object) Schema() string {
 
 // Attr returns the Nth attribute after applying schema spec.
 func (dom *Object) Attr(index int) *Attribute {
-	if index > len(dom.attributes) {
+	if index < 0 || index >= len(dom.attributes) {
 		return nil
 	}
 	a := dom.attributes[index]
@@ -342,7 +474,7 @@ func (attr *Attribute) Text() string {
 func (attr *Attribute) Comment() string {
 	lis := make([]string, 0, len(attr.rows))
 	for _, v := range attr.rows {
-		if c := v.Comment; len(c) > 0 {
+		if _, c := v.split(); len(c) > 0 {
 			lis = append(lis, c)
 		}
 	}
@@ -375,7 +507,7 @@ func (attr *Attribute) Lines() []string {
 
 	lis := make([]string, len(attr.rows))
 	for i, row := range attr.rows {
-		lis[i] = row.Value
+		lis[i], _ = row.split()
 	}
 
 	return lis
@@ -507,6 +639,19 @@ type Value struct {
 
 	// Lineno location in source file.
 	Lineno int
+
+	// raw is the row of a parsed value. Its value and comment are split
+	// when read, so parsing does not pay for it. The row is never
+	// changed in place as parsed objects are shared between readers.
+	raw string
+}
+
+// split returns the value and comment of the row.
+func (v Value) split() (value, comment string) {
+	if v.raw == "" {
+		return v.Value, v.Comment
+	}
+	return splitComment(v.raw)
 }
 
 // NewValue parsed from string with comments.
@@ -520,15 +665,16 @@ func NewValue(s string) Value {
 }
 
 func (v Value) String() string {
-	if len(v.Comment) > 0 {
-		if len(v.Value) == 0 {
-			return "# " + v.Comment
+	value, comment := v.split()
+	if len(comment) > 0 {
+		if len(value) == 0 {
+			return "# " + comment
 		}
 
-		return v.Value + " # " + v.Comment
+		return value + " # " + comment
 	}
 
-	return v.Value
+	return value
 }
 
 // Schema for objects. Defines scructure and value types.
@@ -537,10 +683,14 @@ type Schema struct {
 
 	Name    string
 	Primary string
-	Links   map[string][]string
-	specTx  map[string][]string
-	spec    map[string]Spec
-	Rules   map[string]*Set
+
+	// Keys in the order declared by the key lines of the schema.
+	Keys []string
+
+	Links  map[string][]string
+	specTx map[string][]string
+	spec   map[string]Spec
+	Rules  map[string]*Set
 }
 
 // Spec for key name.
@@ -562,12 +712,12 @@ func (s *Schema) String() string {
 	buf.WriteRune('\n')
 	buf.WriteString("primary: ")
 	buf.WriteString(s.Primary)
-	for key, rules := range s.Rules {
+	for _, key := range s.Keys {
 		buf.WriteRune('\n')
 		buf.WriteString(key)
 		buf.WriteRune(':')
 		buf.WriteRune(' ')
-		buf.WriteString(rules.String())
+		buf.WriteString(s.Rules[key].String())
 	}
 
 	return buf.String()
@@ -615,12 +765,13 @@ func (s *Schemas) Apply(lis ...*Object) {
 	}
 }
 
-// Items to iterate over list of schemas.
+// Items to iterate over list of schemas sorted by name.
 func (s *Schemas) Items() []*Schema {
 	lis := make([]*Schema, 0, len(s.m))
 	for _, schema := range s.m {
 		lis = append(lis, schema)
 	}
+	sort.Slice(lis, func(i, j int) bool { return lis[i].Name < lis[j].Name })
 	return lis
 }
 
@@ -633,89 +784,121 @@ func (s *Schemas) Get(name string) *Schema {
 type Parser struct {
 	scanner *bufio.Scanner
 	current *Object
+
+	// buf holds the lines of the object being scanned with the offset of
+	// the end of each line in ends.
+	buf  []byte
+	ends []int
+
+	// names interns attribute names so objects share them.
+	names map[string]string
 }
 
 // NewParser reading from io Reader.
 func NewParser(in io.Reader) *Parser {
-	return &Parser{scanner: bufio.NewScanner(in)}
+	return &Parser{scanner: bufio.NewScanner(in), names: make(map[string]string)}
 }
 
 // Scan parses a single object and stores it in Current.
 func (p *Parser) Scan() bool {
-	var dom *Object
-
-	lineno := 0
-	found := false
+	p.buf, p.ends = p.buf[:0], p.ends[:0]
 
 	for p.scanner.Scan() {
-		line := p.scanner.Text()
+		line := p.scanner.Bytes()
 
-		if lineno == 0 && line == "" {
-			continue
-		}
-		if lineno > 0 && line == "" {
+		if len(line) == 0 {
+			if len(p.ends) == 0 {
+				continue
+			}
 			break
 		}
-		if !found {
-			found = true
-			dom = &Object{}
-			dom.keys = make(map[string][]int)
-		}
+		p.buf = append(p.buf, line...)
+		p.ends = append(p.ends, len(p.buf))
+	}
 
-		lineno += 1
+	if len(p.ends) == 0 {
+		p.current = nil
+		return false
+	}
 
-		r, _ := utf8.DecodeRuneInString(line)
-		switch r {
-		case ' ', '\t', '+':
-			if len(dom.attributes) == 0 {
-				continue
-			}
-			last := len(dom.attributes) - 1
+	p.current = p.parse(string(p.buf))
+	return true
+}
 
-			if r == '+' {
-				dom.attributes[last].rows = append(
-					dom.attributes[last].rows, Value{Lineno: lineno})
-			} else {
-				sp := strings.SplitN(line, "#", 2)
+// parse the lines of an object held in text. Values are slices of text and
+// attributes and rows are allocated together for the whole object.
+func (p *Parser) parse(text string) *Object {
+	n := len(p.ends)
 
-				v := Value{
-					Lineno: lineno,
-					Value:  strings.TrimSpace(sp[0]),
-				}
-				if len(sp) == 2 {
-					v.Comment = strings.TrimSpace(sp[1])
-				}
+	dom := &Object{
+		attributes: make(ListAttribute, 0, n),
+		keys:       make(map[string][]int),
+	}
+	attrs := make([]Attribute, 0, n)
+	rows := make([]Value, n)
 
-				dom.attributes[last].rows = append(
-					dom.attributes[last].rows, v)
-			}
+	var attr *Attribute
+	first, next, start := 0, 0, 0
 
-		default:
-			sp := strings.SplitN(line, ":", 2)
-			if len(sp) < 2 {
+	for i, end := range p.ends {
+		line := text[start:end]
+		start = end
+
+		var v Value
+		switch line[0] {
+		case ' ', '\t', '+':
+			if attr == nil {
 				continue
 			}
-			attr := &Attribute{Name: strings.TrimSpace(sp[0])}
-			sp = strings.SplitN(sp[1], "#", 2)
-			v := Value{
-				Lineno: lineno,
-				Value:  strings.TrimSpace(sp[0]),
+			if line[0] != '+' {
+				v.raw = line
 			}
-			if len(sp) == 2 {
-				v.Comment = strings.TrimSpace(sp[1])
-			}
-			attr.rows = append(attr.rows, v)
-			if lis, ok := dom.keys[attr.Name]; ok {
-				dom.keys[attr.Name] = append(lis, len(dom.attributes))
-			} else {
-				dom.keys[attr.Name] = []int{len(dom.attributes)}
+
+		default:
+			colon := strings.IndexByte(line, ':')
+			if colon < 0 {
+				continue
 			}
+			v.raw = line[colon+1:]
+
+			attrs = append(attrs, Attribute{Name: p.intern(strings.TrimSpace(line[:colon]))})
+			attr = &attrs[len(attrs)-1]
+			first = next
+
+			dom.keys[attr.Name] = append(dom.keys[attr.Name], len(dom.attributes))
 			dom.attributes = append(dom.attributes, attr)
 		}
+
+		v.Lineno = i + 1
+		rows[next] = v
+		next++
+
+		// Rows of an attribute are consecutive lines. The capacity is
+		// limited so appending to them does not overwrite the next.
+		attr.rows = rows[first:next:next]
 	}
 
-	p.current = dom
-	return found
+	return dom
+}
+
+func (p *Parser) intern(name string) string {
+	if s, ok := p.names[name]; ok {
+		return s
+	}
+
+	// Copy so the interned name does not hold on to the object text.
+	s := string(append([]byte(nil), name...))
+	p.names[s] = s
+
+	return s
+}
+
+// splitComment splits a row into its value and the comment after a #.
+func splitComment(row string) (value, comment string) {
+	if i := strings.IndexByte(row, '#'); i >= 0 {
+		return strings.TrimSpace(row[:i]), strings.TrimSpace(row[i+1:])
+	}
+	return strings.TrimSpace(row), ""
 }
 
 // Current returns last scanned and parsed object.
@@ -733,14 +916,14 @@ type SchemaParser struct {
 }
 
 // NewSchemaParser creates new parser with list of optional primary keys.
-// The primary keys will be derived automatically from parsed schemas.
+// The schema names and primary keys will be derived automatically from parsed schemas.
 func NewSchemaParser(keys ...string) *SchemaParser {
 	return &SchemaParser{keys: NewSet(keys...)}
 }
 
 // ParseSchema from object
 func (p *SchemaParser) ParseSchema(dom *Object) *Schema {
-	schema := &Schema{}
+	schema := &Schema{Object: dom}
 	schema.Links = make(map[string][]string)
 	schema.spec = make(map[string]Spec)
 	schema.specTx = make(map[string][]string)
@@ -760,6 +943,9 @@ func (p *SchemaParser) ParseSchema(dom *Object) *Schema {
 				schema.Primary = key
 			}
 
+			if _, ok := schema.Rules[key]; !ok {
+				schema.Keys = append(schema.Keys, key)
+			}
 			schema.Rules[key] = NewSet()
 			for i, rule := range fields {
 				if rule == ">" {
@@ -786,7 +972,7 @@ func (p *SchemaParser) ParseSchema(dom *Object) *Schema {
 		}
 	}
 
-	p.keys.Add(schema.Primary)
+	p.keys.Add(schema.Name, schema.Primary)
 
 	return schema
 }
@@ -1340,5 +1526,8 @@ func (*nullFS) LoadObject(schema, name string) (*Object, error) {
 func (*nullFS) FindObject(search string) ([]*Object, error) {
 	return nil, NotFound
 }
+func (*nullFS) ListObjects(schema string) (ListObject, error) {
+	return nil, nil
+}
 
 var NotFound = errors.New("object not found")
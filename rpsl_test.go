@@ -1,10 +1,12 @@
 package rpsl_test
 
 import (
+	"bufio"
 	"encoding/json"
 	"net/mail"
 	"strings"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/matryer/is"
 	"rpsl.dn42.us/go-rpsl"
@@ -236,6 +238,54 @@ func TestMarshaler(t *testing.T) {
 	is.Equal(string(b), `[true,123,1.23,"string",["one","three","two"]]`)
 }
 
+func TestObjectEdit(t *testing.T) {
+	is := is.New(t)
+
+	dom := rpsl.ParseObject(cleanDoc(`
+		mntner: XUU-MNT
+		admin-c: XUU-DN42
+		tech-c: XUU-DN42
+		mnt-by: XUU-MNT
+		auth: pgp-fingerprint 1234
+		auth: ssh-ed25519 AAAA
+		source: DN42
+	`))
+
+	names := func() string {
+		var lis []string
+		for _, attr := range dom.Attrs() {
+			lis = append(lis, attr.Name)
+		}
+		return strings.Join(lis, ",")
+	}
+
+	dom.Delete("admin-c")
+	dom.DeleteN("auth", 0)
+	is.Equal(names(), "mntner,tech-c,mnt-by,auth,source")
+	is.Equal(dom.Get("auth").Text(), "ssh-ed25519 AAAA")
+	is.Equal(dom.Attr(4).Name, "source")
+	is.Equal(dom.Attr(5), nil)
+	is.Equal(dom.Attr(-1), nil)
+
+	dom.InsertAfter(0, "descr", "Xuu")
+	dom.InsertBefore(4, "auth", "pgp-fingerprint 5678")
+	is.Equal(names(), "mntner,descr,tech-c,mnt-by,auth,auth,source")
+	is.Equal(dom.GetN("auth", 0).Text(), "pgp-fingerprint 5678")
+	is.Equal(dom.GetN("auth", 1).Text(), "ssh-ed25519 AAAA")
+
+	dom.Move(3, 1)
+	dom.Move(5, 6)
+	is.Equal(names(), "mntner,mnt-by,descr,tech-c,auth,source,auth")
+	is.Equal(dom.GetN("auth", 1).Text(), "ssh-ed25519 AAAA")
+
+	dom.DeleteAll("auth", 0)
+	dom.Add("remarks", "end")
+	is.Equal(names(), "mntner,mnt-by,descr,tech-c,source,remarks")
+	is.Equal(len(dom.GetAll("auth")), 0)
+	is.Equal(dom.GetAll("remarks")[0].Text(), "end")
+	is.Equal(dom.Get("source").Text(), "DN42")
+}
+
 func TestSet(t *testing.T) {
 	is := is.New(t)
 
@@ -581,3 +631,152 @@ func TestSpecRule(t *testing.T) {
 		}
 	}
 }
+
+func BenchmarkParseAll(b *testing.B) {
+	doc := strings.Repeat(cleanDoc(txtAllObjects)+"\n", 100)
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		rpsl.ParseAll(strings.NewReader(doc))
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	is := is.New(t)
+
+	schemas, err := rpsl.ParseSchemas(rpsl.ParseAll(strings.NewReader(cleanDoc(`
+		schema: MNTNER-SCHEMA
+		key:    mntner  required single primary
+		key:    descr   optional multiple
+		key:    admin-c optional multiple
+		key:    mnt-by  required multiple
+		key:    auth    optional multiple
+		key:    source  required single
+	`))))
+	is.NoErr(err)
+	is.Equal(schemas.Get("mntner").Keys, []string{"mntner", "descr", "admin-c", "mnt-by", "auth", "source"})
+
+	dom := rpsl.ParseObject(cleanDoc(`
+		mntner: XUU-MNT
+		source: DN42
+		auth: pgp-fingerprint 1234
+		remarks: unknown
+		mnt-by: XUU-MNT
+		auth: ssh-ed25519 AAAA
+		descr: Xuu
+	`))
+
+	// Without a schema the object is unchanged.
+	txt := dom.String()
+	dom.Normalize()
+	is.Equal(dom.String(), txt)
+
+	schemas.Apply(dom)
+	dom.Normalize()
+	is.Equal(dom.String(), cleanDoc(`
+		mntner:             XUU-MNT
+		descr:              Xuu
+		mnt-by:             XUU-MNT
+		auth:               pgp-fingerprint 1234
+		auth:               ssh-ed25519 AAAA
+		source:             DN42
+		remarks:            unknown`))
+	is.Equal(dom.GetN("auth", 1).Text(), "ssh-ed25519 AAAA")
+}
+
+// BenchmarkParseAllBaseline parses the same input as BenchmarkParseAll with
+// a copy of the line scanner used before objects were parsed from byte
+// slices, to compare the two.
+func BenchmarkParseAllBaseline(b *testing.B) {
+	doc := strings.Repeat(cleanDoc(txtAllObjects)+"\n", 100)
+	b.SetBytes(int64(len(doc)))
+	b.ReportAllocs()
+
+	for i := 0; i < b.N; i++ {
+		s := bufio.NewScanner(strings.NewReader(doc))
+		for {
+			dom, ok := baselineScan(s)
+			if !ok {
+				break
+			}
+			_ = dom
+		}
+	}
+}
+
+type baselineValue struct {
+	Value, Comment string
+	Lineno         int
+}
+
+type baselineAttribute struct {
+	Name string
+	rows []baselineValue
+}
+
+type baselineObject struct {
+	attributes []*baselineAttribute
+	keys       map[string][]int
+}
+
+func baselineScan(scanner *bufio.Scanner) (*baselineObject, bool) {
+	var dom *baselineObject
+
+	lineno := 0
+	found := false
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if lineno == 0 && line == "" {
+			continue
+		}
+		if lineno > 0 && line == "" {
+			break
+		}
+		if !found {
+			found = true
+			dom = &baselineObject{keys: make(map[string][]int)}
+		}
+
+		lineno++
+
+		r, _ := utf8.DecodeRuneInString(line)
+		switch r {
+		case ' ', '\t', '+':
+			if len(dom.attributes) == 0 {
+				continue
+			}
+			last := dom.attributes[len(dom.attributes)-1]
+
+			if r == '+' {
+				last.rows = append(last.rows, baselineValue{Lineno: lineno})
+			} else {
+				sp := strings.SplitN(line, "#", 2)
+				v := baselineValue{Lineno: lineno, Value: strings.TrimSpace(sp[0])}
+				if len(sp) == 2 {
+					v.Comment = strings.TrimSpace(sp[1])
+				}
+				last.rows = append(last.rows, v)
+			}
+
+		default:
+			sp := strings.SplitN(line, ":", 2)
+			if len(sp) < 2 {
+				continue
+			}
+			attr := &baselineAttribute{Name: strings.TrimSpace(sp[0])}
+			sp = strings.SplitN(sp[1], "#", 2)
+			v := baselineValue{Lineno: lineno, Value: strings.TrimSpace(sp[0])}
+			if len(sp) == 2 {
+				v.Comment = strings.TrimSpace(sp[1])
+			}
+			attr.rows = append(attr.rows, v)
+			dom.keys[attr.Name] = append(dom.keys[attr.Name], len(dom.attributes))
+			dom.attributes = append(dom.attributes, attr)
+		}
+	}
+
+	return dom, found
+}
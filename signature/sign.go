@@ -0,0 +1,190 @@
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha512"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+const opensshMagic = "openssh-key-v1\x00"
+
+// Sign message with an ed25519 or rsa private key using the sha512 hash.
+func Sign(key crypto.Signer, message []byte) (*Signature, error) {
+	pub, err := MarshalPublicKey(key.Public())
+	if err != nil {
+		return nil, err
+	}
+
+	s := &Signature{PublicKey: pub, Namespace: Namespace, HashAlgorithm: "sha512"}
+	data, err := s.SignedData(message)
+	if err != nil {
+		return nil, err
+	}
+
+	switch key.Public().(type) {
+	case ed25519.PublicKey:
+		s.Format = "ssh-ed25519"
+		s.Blob, err = key.Sign(rand.Reader, data, crypto.Hash(0))
+	case *rsa.PublicKey:
+		s.Format = "rsa-sha2-512"
+		d := sha512.Sum512(data)
+		s.Blob, err = key.Sign(rand.Reader, d[:], crypto.SHA512)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+
+	return s, nil
+}
+
+// SignObjects signs the serialisation of lis and returns a signature object.
+func SignObjects(lis rpsl.ListObject, key crypto.Signer) (*rpsl.Object, error) {
+	s, err := Sign(key, Message(lis))
+	if err != nil {
+		return nil, err
+	}
+
+	return rpsl.ParseObject(Schema + ": " + s.String()), nil
+}
+
+// Append a signature over the signed objects of lis. Existing signature
+// objects are left in place so that several maintainers can sign the same
+// object or transaction envelope. In an envelope the signature is placed
+// before transaction-submit-end so that it is parsed as part of the
+// transaction.
+func Append(lis rpsl.ListObject, key crypto.Signer) (rpsl.ListObject, error) {
+	signed, _ := Split(lis)
+
+	sig, err := SignObjects(signed, key)
+	if err != nil {
+		return nil, err
+	}
+
+	i := len(lis)
+	for j := len(lis) - 1; j >= 0; j-- {
+		if lis[j].Schema() == "transaction-submit-end" {
+			i = j
+			break
+		}
+	}
+
+	out := make(rpsl.ListObject, 0, len(lis)+1)
+	out = append(out, lis[:i]...)
+	out = append(out, sig)
+	return append(out, lis[i:]...), nil
+}
+
+// ParsePrivateKey parses an unencrypted ed25519 or rsa private key in OpenSSH,
+// PKCS#8 or PKCS#1 PEM format.
+func ParsePrivateKey(b []byte) (crypto.Signer, error) {
+	block, _ := pem.Decode(b)
+	if block == nil {
+		return nil, errors.New("signature: no PEM data found")
+	}
+
+	switch block.Type {
+	case "OPENSSH PRIVATE KEY":
+		return parseOpenSSH(block.Bytes)
+
+	case "RSA PRIVATE KEY":
+		key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("signature: %w", err)
+		}
+		return key, nil
+
+	case "PRIVATE KEY":
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("signature: %w", err)
+		}
+		switch key := key.(type) {
+		case ed25519.PrivateKey:
+			return key, nil
+		case *rsa.PrivateKey:
+			return key, nil
+		}
+		return nil, fmt.Errorf("signature: unsupported key type %T", key)
+
+	default:
+		return nil, fmt.Errorf("signature: unsupported PEM type %q", block.Type)
+	}
+}
+
+// parseOpenSSH decodes the openssh-key-v1 format written by ssh-keygen.
+func parseOpenSSH(b []byte) (crypto.Signer, error) {
+	if !bytes.HasPrefix(b, []byte(opensshMagic)) {
+		return nil, errors.New("signature: invalid openssh private key")
+	}
+	r := &reader{b: b[len(opensshMagic):]}
+
+	cipher := string(r.bytes())
+	kdf := string(r.bytes())
+	r.bytes() // kdf options
+	if r.err == nil && (cipher != "none" || kdf != "none") {
+		return nil, errors.New("signature: encrypted private keys are not supported")
+	}
+	if n := r.uint32(); r.err == nil && n != 1 {
+		return nil, fmt.Errorf("signature: expected one private key, found %d", n)
+	}
+	r.bytes() // public key
+
+	p := &reader{b: r.bytes()}
+	if p.uint32() != p.uint32() {
+		return nil, errors.New("signature: invalid openssh private key")
+	}
+
+	var key crypto.Signer
+	switch typ := string(p.bytes()); typ {
+	case "ssh-ed25519":
+		p.bytes() // public key
+		priv := p.bytes()
+		if p.err == nil && len(priv) != ed25519.PrivateKeySize {
+			return nil, errors.New("signature: invalid ed25519 private key")
+		}
+		key = ed25519.PrivateKey(priv)
+
+	case "ssh-rsa":
+		n := new(big.Int).SetBytes(p.bytes())
+		e := new(big.Int).SetBytes(p.bytes())
+		d := new(big.Int).SetBytes(p.bytes())
+		p.bytes() // iqmp
+		p1 := new(big.Int).SetBytes(p.bytes())
+		q := new(big.Int).SetBytes(p.bytes())
+		if p.err != nil || !e.IsInt64() {
+			break
+		}
+
+		rsaKey := &rsa.PrivateKey{
+			PublicKey: rsa.PublicKey{N: n, E: int(e.Int64())},
+			D:         d,
+			Primes:    []*big.Int{p1, q},
+		}
+		if err := rsaKey.Validate(); err != nil {
+			return nil, fmt.Errorf("signature: %w", err)
+		}
+		rsaKey.Precompute()
+		key = rsaKey
+
+	default:
+		if p.err == nil {
+			return nil, fmt.Errorf("signature: unsupported key type %q", typ)
+		}
+	}
+
+	if r.err != nil || p.err != nil || key == nil {
+		return nil, errors.New("signature: truncated openssh private key")
+	}
+
+	return key, nil
+}
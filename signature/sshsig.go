@@ -0,0 +1,304 @@
+// Package signature verifies and creates SSH signatures over registry objects
+// using the sshsig format of OpenSSH.
+package signature
+
+import (
+	"bytes"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash"
+	"math/big"
+	"strings"
+)
+
+// Namespace used for signatures over registry objects.
+const Namespace = "rpsl"
+
+const (
+	magic   = "SSHSIG"
+	version = 1
+
+	armorBegin = "-----BEGIN SSH SIGNATURE-----"
+	armorEnd   = "-----END SSH SIGNATURE-----"
+)
+
+// Signature is a parsed sshsig signature.
+type Signature struct {
+	// PublicKey of the signer in SSH wire format.
+	PublicKey     []byte
+	Namespace     string
+	HashAlgorithm string
+
+	// Format of the signature blob such as ssh-ed25519 or rsa-sha2-512.
+	Format string
+	Blob   []byte
+}
+
+// Parse a signature from its armored or base64 encoded form.
+func Parse(text string) (*Signature, error) {
+	text = strings.TrimSpace(text)
+	text = strings.TrimPrefix(text, armorBegin)
+	text = strings.TrimSuffix(text, armorEnd)
+	text = strings.Join(strings.Fields(text), "")
+
+	b, err := base64.StdEncoding.DecodeString(text)
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+
+	return Unmarshal(b)
+}
+
+// Unmarshal a signature from the sshsig binary format.
+func Unmarshal(b []byte) (*Signature, error) {
+	if !bytes.HasPrefix(b, []byte(magic)) {
+		return nil, errors.New("signature: missing SSHSIG preamble")
+	}
+	r := &reader{b: b[len(magic):]}
+
+	if v := r.uint32(); v != version {
+		return nil, fmt.Errorf("signature: unsupported version %d", v)
+	}
+
+	s := &Signature{}
+	s.PublicKey = r.bytes()
+	s.Namespace = string(r.bytes())
+	r.bytes() // reserved
+	s.HashAlgorithm = string(r.bytes())
+
+	blob := &reader{b: r.bytes()}
+	s.Format = string(blob.bytes())
+	s.Blob = blob.bytes()
+
+	if r.err != nil || blob.err != nil {
+		return nil, errors.New("signature: truncated signature")
+	}
+
+	return s, nil
+}
+
+// Marshal the signature into the sshsig binary format.
+func (s *Signature) Marshal() []byte {
+	var blob bytes.Buffer
+	writeBytes(&blob, []byte(s.Format))
+	writeBytes(&blob, s.Blob)
+
+	var b bytes.Buffer
+	b.WriteString(magic)
+	binary.Write(&b, binary.BigEndian, uint32(version))
+	writeBytes(&b, s.PublicKey)
+	writeBytes(&b, []byte(s.Namespace))
+	writeBytes(&b, nil)
+	writeBytes(&b, []byte(s.HashAlgorithm))
+	writeBytes(&b, blob.Bytes())
+
+	return b.Bytes()
+}
+
+// String returns the signature base64 encoded on a single line.
+func (s *Signature) String() string {
+	return base64.StdEncoding.EncodeToString(s.Marshal())
+}
+
+// Armor returns the signature in the armored form written by ssh-keygen.
+func (s *Signature) Armor() string {
+	text := s.String()
+
+	var b strings.Builder
+	b.WriteString(armorBegin)
+	for len(text) > 70 {
+		b.WriteString("\n" + text[:70])
+		text = text[70:]
+	}
+	b.WriteString("\n" + text + "\n" + armorEnd + "\n")
+
+	return b.String()
+}
+
+// SignedData returns the data covered by the signature for message.
+func (s *Signature) SignedData(message []byte) ([]byte, error) {
+	h, err := newHash(s.HashAlgorithm)
+	if err != nil {
+		return nil, err
+	}
+	h.Write(message)
+
+	var b bytes.Buffer
+	b.WriteString(magic)
+	writeBytes(&b, []byte(s.Namespace))
+	writeBytes(&b, nil)
+	writeBytes(&b, []byte(s.HashAlgorithm))
+	writeBytes(&b, h.Sum(nil))
+
+	return b.Bytes(), nil
+}
+
+// Verify the signature over message with the embedded public key.
+func (s *Signature) Verify(message []byte) error {
+	if s.Namespace != Namespace {
+		return fmt.Errorf("signature: namespace %q is not %q", s.Namespace, Namespace)
+	}
+
+	data, err := s.SignedData(message)
+	if err != nil {
+		return err
+	}
+
+	key, err := ParsePublicKey(s.PublicKey)
+	if err != nil {
+		return err
+	}
+
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		if s.Format != "ssh-ed25519" {
+			return fmt.Errorf("signature: format %s does not match ed25519 key", s.Format)
+		}
+		if !ed25519.Verify(key, data, s.Blob) {
+			return ErrInvalid
+		}
+		return nil
+
+	case *rsa.PublicKey:
+		var h crypto.Hash
+		switch s.Format {
+		case "rsa-sha2-256":
+			h = crypto.SHA256
+		case "rsa-sha2-512":
+			h = crypto.SHA512
+		default:
+			return fmt.Errorf("signature: format %s does not match rsa key", s.Format)
+		}
+		d := h.New()
+		d.Write(data)
+		if rsa.VerifyPKCS1v15(key, h, d.Sum(nil), s.Blob) != nil {
+			return ErrInvalid
+		}
+		return nil
+	}
+
+	return fmt.Errorf("signature: unsupported key type %T", key)
+}
+
+// ErrInvalid is returned when a signature does not match the message.
+var ErrInvalid = errors.New("signature: invalid signature")
+
+// ParsePublicKey parses an ssh-ed25519 or ssh-rsa public key in SSH wire format.
+func ParsePublicKey(b []byte) (crypto.PublicKey, error) {
+	r := &reader{b: b}
+
+	switch typ := string(r.bytes()); typ {
+	case "ssh-ed25519":
+		key := r.bytes()
+		if r.err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, errors.New("signature: invalid ed25519 public key")
+		}
+		return ed25519.PublicKey(key), nil
+
+	case "ssh-rsa":
+		e := new(big.Int).SetBytes(r.bytes())
+		n := new(big.Int).SetBytes(r.bytes())
+		if r.err != nil || !e.IsInt64() {
+			return nil, errors.New("signature: invalid rsa public key")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+
+	default:
+		return nil, fmt.Errorf("signature: unsupported key type %q", typ)
+	}
+}
+
+// MarshalPublicKey encodes an ed25519 or rsa public key in SSH wire format.
+func MarshalPublicKey(key crypto.PublicKey) ([]byte, error) {
+	var b bytes.Buffer
+
+	switch key := key.(type) {
+	case ed25519.PublicKey:
+		writeBytes(&b, []byte("ssh-ed25519"))
+		writeBytes(&b, key)
+
+	case *rsa.PublicKey:
+		writeBytes(&b, []byte("ssh-rsa"))
+		writeBytes(&b, mpint(big.NewInt(int64(key.E))))
+		writeBytes(&b, mpint(key.N))
+
+	default:
+		return nil, fmt.Errorf("signature: unsupported key type %T", key)
+	}
+
+	return b.Bytes(), nil
+}
+
+// ParseAuthorizedKey parses the type and base64 key of an authorized_keys
+// line or mntner auth attribute and returns the key in SSH wire format.
+func ParseAuthorizedKey(typ, data string) ([]byte, error) {
+	b, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, fmt.Errorf("signature: %w", err)
+	}
+
+	r := &reader{b: b}
+	if t := string(r.bytes()); r.err != nil || t != typ {
+		return nil, fmt.Errorf("signature: key type %q does not match %q", t, typ)
+	}
+
+	return b, nil
+}
+
+func newHash(name string) (hash.Hash, error) {
+	switch name {
+	case "sha256":
+		return sha256.New(), nil
+	case "sha512":
+		return sha512.New(), nil
+	default:
+		return nil, fmt.Errorf("signature: unsupported hash algorithm %q", name)
+	}
+}
+
+type reader struct {
+	b   []byte
+	err error
+}
+
+func (r *reader) uint32() uint32 {
+	if len(r.b) < 4 {
+		r.err = errors.New("short read")
+		return 0
+	}
+	v := binary.BigEndian.Uint32(r.b)
+	r.b = r.b[4:]
+	return v
+}
+
+func (r *reader) bytes() []byte {
+	n := r.uint32()
+	if r.err != nil || uint32(len(r.b)) < n {
+		r.err = errors.New("short read")
+		return nil
+	}
+	v := r.b[:n]
+	r.b = r.b[n:]
+	return v
+}
+
+// mpint encodes a positive integer with a leading zero when the high bit is set.
+func mpint(n *big.Int) []byte {
+	b := n.Bytes()
+	if len(b) > 0 && b[0]&0x80 != 0 {
+		return append([]byte{0}, b...)
+	}
+	return b
+}
+
+func writeBytes(b *bytes.Buffer, v []byte) {
+	binary.Write(b, binary.BigEndian, uint32(len(v)))
+	b.Write(v)
+}
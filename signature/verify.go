@@ -0,0 +1,114 @@
+package signature
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+// Schema of the objects holding signatures.
+const Schema = "signature"
+
+// ErrNoMatch is returned when no maintainer auth line verifies a signature.
+var ErrNoMatch = errors.New("signature: no matching auth key")
+
+// Match is the maintainer auth line that verified a signature.
+type Match struct {
+	Mntner string
+	Auth   string
+
+	// Index of the auth attribute within the mntner object.
+	Index int
+}
+
+// Split a list of objects into signed objects and signature objects.
+func Split(lis rpsl.ListObject) (signed, sigs rpsl.ListObject) {
+	for _, dom := range lis {
+		if dom.Schema() == Schema {
+			sigs = append(sigs, dom)
+			continue
+		}
+		signed = append(signed, dom)
+	}
+	return signed, sigs
+}
+
+// Message returns the canonical form of objects covered by a signature.
+func Message(lis rpsl.ListObject) []byte {
+	return []byte(lis.Canonical())
+}
+
+// FromObject parses the signature held by a signature object.
+func FromObject(dom *rpsl.Object) (*Signature, error) {
+	if dom.Schema() != Schema {
+		return nil, fmt.Errorf("signature: object %s is not a signature", dom.Schema())
+	}
+
+	return Parse(dom.Get(Schema).Text())
+}
+
+// Verifier checks signatures against the auth keys of maintainers.
+type Verifier struct {
+	fetch rpsl.Fetcher
+}
+
+// NewVerifier loads maintainers from f.
+func NewVerifier(f rpsl.Fetcher) *Verifier {
+	return &Verifier{fetch: f}
+}
+
+// Verify the signature object over signed with the auth keys of mntners.
+// The first auth line that verifies the signature is returned.
+func (v *Verifier) Verify(signed rpsl.ListObject, sig *rpsl.Object, mntners ...string) (*Match, error) {
+	s, err := FromObject(sig)
+	if err != nil {
+		return nil, err
+	}
+	message := Message(signed)
+
+	for _, name := range mntners {
+		mnt, err := v.fetch.LoadObject("mntner", name)
+		if err == rpsl.NotFound {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		for i, auth := range mnt.GetAll("auth") {
+			err := VerifyAuth(auth, s, message)
+			if err == ErrNoMatch {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("mntner %s auth %d: %w", name, i, err)
+			}
+
+			return &Match{Mntner: mnt.Name(), Auth: auth.Text(), Index: i}, nil
+		}
+	}
+
+	return nil, ErrNoMatch
+}
+
+// VerifyAuth checks a signature against a single mntner auth attribute. If the
+// auth line is not a valid SSH key or not the signing key ErrNoMatch is
+// returned.
+func VerifyAuth(auth *rpsl.Attribute, s *Signature, message []byte) error {
+	fields := auth.Fields()
+	if len(fields) < 2 || (fields[0] != "ssh-ed25519" && fields[0] != "ssh-rsa") {
+		return ErrNoMatch
+	}
+
+	key, err := ParseAuthorizedKey(fields[0], fields[1])
+	if err != nil {
+		return ErrNoMatch
+	}
+	if !bytes.Equal(key, s.PublicKey) {
+		return ErrNoMatch
+	}
+
+	return s.Verify(message)
+}
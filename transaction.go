@@ -0,0 +1,153 @@
+package rpsl
+
+import (
+	"fmt"
+	"io"
+)
+
+// Transaction is a set of objects submitted as one unit. In text form the
+// objects are bracketed by transaction-submit-begin and
+// transaction-submit-end markers.
+type Transaction struct {
+	Database string
+	ID       string
+
+	// Begin marker with response-auth-type and transaction-confirm-type.
+	Begin *Object
+	// Label is the optional transaction-label following the begin marker.
+	Label *Object
+	// Confirm is the transaction-confirm object when the transaction was confirmed.
+	Confirm *Object
+
+	Objects ListObject
+}
+
+// NewTransaction creates a transaction for database containing lis.
+func NewTransaction(database, id string, lis ListObject) *Transaction {
+	begin := &Object{keys: make(map[string][]int)}
+	begin.Add("transaction-submit-begin", database+" "+id)
+
+	return &Transaction{
+		Database: database,
+		ID:       id,
+		Begin:    begin,
+		Objects:  lis,
+	}
+}
+
+// ConfirmType returns the transaction-confirm-type of the begin marker.
+func (tx *Transaction) ConfirmType() string {
+	return tx.Begin.Get("transaction-confirm-type").DefaultText("none")
+}
+
+// ListObject returns the envelope with markers around the objects.
+func (tx *Transaction) ListObject() ListObject {
+	end := &Object{keys: make(map[string][]int)}
+	end.Add("transaction-submit-end", tx.Database+" "+tx.ID)
+
+	lis := make(ListObject, 0, len(tx.Objects)+4)
+	lis = append(lis, tx.Begin)
+	if tx.Label != nil {
+		lis = append(lis, tx.Label)
+	}
+	lis = append(lis, tx.Objects...)
+	lis = append(lis, end)
+	if tx.Confirm != nil {
+		lis = append(lis, tx.Confirm)
+	}
+
+	return lis
+}
+
+func (tx *Transaction) String() string {
+	return tx.ListObject().String()
+}
+
+// ParseTransaction reads a single transaction envelope.
+func ParseTransaction(in io.Reader) (*Transaction, error) {
+	lis, err := ParseTransactions(in)
+	if err != nil {
+		return nil, err
+	}
+	if len(lis) != 1 {
+		return nil, fmt.Errorf("transaction: expected one transaction, found %d", len(lis))
+	}
+
+	return lis[0], nil
+}
+
+// ParseTransactions reads a stream of transaction envelopes. A
+// transaction-confirm following a transaction is attached to it.
+func ParseTransactions(in io.Reader) ([]*Transaction, error) {
+	var lis []*Transaction
+	var tx *Transaction
+
+	p := NewParser(in)
+	for p.Scan() {
+		dom := p.Current()
+
+		switch dom.Schema() {
+		case "transaction-submit-begin":
+			if tx != nil {
+				return nil, fmt.Errorf("transaction: begin %s inside transaction %s", dom.Name(), tx.ID)
+			}
+			database, id, err := markerIDs(dom)
+			if err != nil {
+				return nil, err
+			}
+			tx = &Transaction{Database: database, ID: id, Begin: dom}
+
+		case "transaction-label":
+			if tx == nil || tx.Label != nil || len(tx.Objects) > 0 {
+				return nil, fmt.Errorf("transaction: label %s must follow transaction begin", dom.Name())
+			}
+			tx.Label = dom
+
+		case "transaction-submit-end":
+			if tx == nil {
+				return nil, fmt.Errorf("transaction: end without begin")
+			}
+			database, id, err := markerIDs(dom)
+			if err != nil {
+				return nil, err
+			}
+			if database != tx.Database || id != tx.ID {
+				return nil, fmt.Errorf("transaction: end %s %s does not match begin %s %s", database, id, tx.Database, tx.ID)
+			}
+			lis = append(lis, tx)
+			tx = nil
+
+		case "transaction-confirm":
+			database, id, err := markerIDs(dom)
+			if err != nil {
+				return nil, err
+			}
+			if tx != nil || len(lis) == 0 || lis[len(lis)-1].Database != database || lis[len(lis)-1].ID != id {
+				return nil, fmt.Errorf("transaction: confirm %s %s does not follow its transaction", database, id)
+			}
+			lis[len(lis)-1].Confirm = dom
+
+		default:
+			if tx == nil {
+				return nil, fmt.Errorf("transaction: object %s %s outside of transaction", dom.Schema(), dom.Name())
+			}
+			tx.Objects = append(tx.Objects, dom)
+		}
+	}
+
+	if tx != nil {
+		return nil, fmt.Errorf("transaction: missing end for %s %s", tx.Database, tx.ID)
+	}
+
+	return lis, nil
+}
+
+// markerIDs returns the database and transaction id of a marker object.
+func markerIDs(dom *Object) (string, string, error) {
+	fields := dom.Get(dom.Schema()).Fields()
+	if len(fields) != 2 {
+		return "", "", fmt.Errorf("transaction: %s requires database and tx id", dom.Schema())
+	}
+
+	return fields[0], fields[1], nil
+}
@@ -0,0 +1,85 @@
+package rpsl_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestTransaction(t *testing.T) {
+	is := is.New(t)
+
+	s := cleanDoc(`
+        transaction-submit-begin: DN42 1234
+        transaction-confirm-type: normal
+
+        transaction-label:  DN42
+        sequence:           1
+
+        ` + txtMnterObject + `
+        ` + txtRoleObject + `
+        transaction-submit-end: DN42 1234
+
+        transaction-confirm: DN42 1234
+        `)
+
+	tx, err := rpsl.ParseTransaction(strings.NewReader(s))
+	is.NoErr(err)
+	is.Equal(tx.Database, "DN42")
+	is.Equal(tx.ID, "1234")
+	is.Equal(tx.ConfirmType(), "normal")
+	is.Equal(tx.Label.Get("sequence").Text(), "1")
+	is.True(tx.Confirm != nil)
+	is.Equal(len(tx.Objects), 2)
+	is.Equal(tx.Objects[0].Name(), "XUU-MNT")
+
+	again, err := rpsl.ParseTransaction(strings.NewReader(tx.String()))
+	is.NoErr(err)
+	is.Equal(again.String(), tx.String())
+
+	lis := rpsl.ParseAll(strings.NewReader(cleanDoc(txtSourisObjects)))
+	tx = rpsl.NewTransaction("DN42", "5678", lis)
+	is.Equal(tx.ConfirmType(), "none")
+	is.Equal(len(tx.ListObject()), len(lis)+2)
+
+	all, err := rpsl.ParseTransactions(strings.NewReader(tx.String() + "\n\n" + tx.String()))
+	is.NoErr(err)
+	is.Equal(len(all), 2)
+	is.Equal(all[1].ID, "5678")
+	is.Equal(len(all[1].Objects), len(lis))
+
+	f, err := os.Open("schema.txt")
+	is.NoErr(err)
+	defer f.Close()
+
+	schemas, err := rpsl.ParseSchemas(rpsl.ParseAll(f))
+	is.NoErr(err)
+	schemas.Apply(tx.Begin)
+	is.Equal(tx.Begin.Get("transaction-submit-begin").Args().String(), `database:"DN42" tx:"5678"`)
+}
+
+func TestTransactionErrors(t *testing.T) {
+	is := is.New(t)
+
+	tests := []string{
+		"mntner: XUU-MNT",
+		"transaction-submit-begin: DN42 1\n\nmntner: XUU-MNT",
+		"transaction-submit-begin: DN42 1\n\ntransaction-submit-begin: DN42 2",
+		"transaction-submit-begin: DN42 1\n\ntransaction-submit-end: DN42 2",
+		"transaction-submit-begin: DN42\n\ntransaction-submit-end: DN42",
+		"transaction-submit-end: DN42 1",
+		"transaction-confirm: DN42 1",
+		"transaction-submit-begin: DN42 1\n\nmntner: XUU-MNT\n\ntransaction-label: DN42",
+	}
+
+	for _, tt := range tests {
+		_, err := rpsl.ParseTransactions(strings.NewReader(tt))
+		is.True(err != nil)
+	}
+
+	_, err := rpsl.ParseTransaction(strings.NewReader(""))
+	is.True(err != nil)
+}
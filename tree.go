@@ -0,0 +1,184 @@
+package rpsl
+
+import (
+	"sort"
+	"strings"
+)
+
+// ObjectChange is a created (DiffAdd), deleted (DiffRemove), modified
+// (DiffModify) or renamed (DiffMove) object between two registries. OldName
+// is set for renames and Diff for modifications and renames.
+type ObjectChange struct {
+	Op      DiffOp      `json:"op"`
+	Schema  string      `json:"schema"`
+	Name    string      `json:"name"`
+	OldName string      `json:"old_name,omitempty"`
+	Diff    *ObjectDiff `json:"diff,omitempty"`
+
+	Old *Object `json:"-"`
+	New *Object `json:"-"`
+}
+
+// TreeDiff lists the changed objects between two registries ordered by schema
+// and name.
+type TreeDiff struct {
+	Objects []*ObjectChange `json:"objects"`
+}
+
+// DiffTree compares every object of the registries old and new. The schemas
+// compared are those defined by schema objects in either registry. A deleted
+// and a created object of the same schema that differ only in their schema and
+// primary key attributes are reported as a rename.
+func DiffTree(old, new Lister) (*TreeDiff, error) {
+	names, err := treeSchemas(old, new)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &TreeDiff{}
+	for _, schema := range names {
+		before, err := old.ListObjects(schema)
+		if err != nil {
+			return nil, err
+		}
+		after, err := new.ListObjects(schema)
+		if err != nil {
+			return nil, err
+		}
+
+		t.Objects = append(t.Objects, diffSchema(schema, before, after)...)
+	}
+
+	return t, nil
+}
+
+func treeSchemas(registries ...Lister) ([]string, error) {
+	set := NewSet("schema")
+	for _, r := range registries {
+		lis, err := r.ListObjects("schema")
+		if err != nil {
+			return nil, err
+		}
+		schemas, err := ParseSchemas(lis)
+		if err != nil {
+			return nil, err
+		}
+		for _, schema := range schemas.Items() {
+			set.Add(schema.Name)
+		}
+	}
+
+	return set.Members(), nil
+}
+
+func diffSchema(schema string, before, after ListObject) []*ObjectChange {
+	old := make(map[string]*Object, len(before))
+	for _, dom := range before {
+		old[dom.Name()] = dom
+	}
+
+	var lis, created []*ObjectChange
+	seen := make(map[string]bool, len(after))
+	for _, dom := range after {
+		name := dom.Name()
+		seen[name] = true
+
+		prev, ok := old[name]
+		if !ok {
+			created = append(created, &ObjectChange{Op: DiffAdd, Schema: schema, Name: name, New: dom})
+			continue
+		}
+		if d := Diff(prev, dom); !d.Empty() {
+			lis = append(lis, &ObjectChange{Op: DiffModify, Schema: schema, Name: name, Diff: d, Old: prev, New: dom})
+		}
+	}
+
+	var deleted []*ObjectChange
+	for _, dom := range before {
+		if !seen[dom.Name()] {
+			deleted = append(deleted, &ObjectChange{Op: DiffRemove, Schema: schema, Name: dom.Name(), Old: dom})
+		}
+	}
+
+	for _, c := range created {
+		for i, r := range deleted {
+			if renameKey(r.Old) != renameKey(c.New) {
+				continue
+			}
+			c.Op, c.OldName, c.Old = DiffMove, r.Name, r.Old
+			c.Diff = Diff(r.Old, c.New)
+			deleted = append(deleted[:i], deleted[i+1:]...)
+			break
+		}
+	}
+
+	lis = append(append(lis, created...), deleted...)
+	sort.SliceStable(lis, func(i, j int) bool { return lis[i].Name < lis[j].Name })
+
+	return lis
+}
+
+// renameKey is the canonical form of an object without its schema and
+// primary key attributes.
+func renameKey(dom *Object) string {
+	skip := NewSet(dom.Schema(), dom.Primary())
+
+	var b strings.Builder
+	for _, attr := range dom.present() {
+		if !skip.Has(attr.Name) {
+			attr.canonical(&b)
+		}
+	}
+	return b.String()
+}
+
+// BySchema groups the changed objects by schema.
+func (t *TreeDiff) BySchema() map[string][]*ObjectChange {
+	m := make(map[string][]*ObjectChange)
+	for _, c := range t.Objects {
+		m[c.Schema] = append(m[c.Schema], c)
+	}
+	return m
+}
+
+// Changes converts the tree diff into changes for ApplyChanges. Renames become
+// a deletion of the old name and a creation of the new.
+func (t *TreeDiff) Changes() []*Change {
+	var lis []*Change
+	for _, c := range t.Objects {
+		switch c.Op {
+		case DiffAdd:
+			lis = append(lis, &Change{Type: ChangeCreate, Object: c.New})
+		case DiffModify:
+			lis = append(lis, &Change{Type: ChangeModify, Object: c.New})
+		case DiffRemove:
+			lis = append(lis, &Change{Type: ChangeDelete, Object: c.Old})
+		case DiffMove:
+			lis = append(lis, &Change{Type: ChangeDelete, Object: c.Old}, &Change{Type: ChangeCreate, Object: c.New})
+		}
+	}
+	return lis
+}
+
+// String lists the changed objects one per line.
+func (t *TreeDiff) String() string {
+	var b strings.Builder
+	for _, c := range t.Objects {
+		b.WriteString(c.String())
+		b.WriteByte('\n')
+	}
+	return b.String()
+}
+
+func (c *ObjectChange) String() string {
+	switch c.Op {
+	case DiffAdd:
+		return "created " + c.Schema + " " + c.Name
+	case DiffRemove:
+		return "deleted " + c.Schema + " " + c.Name
+	case DiffMove:
+		return "renamed " + c.Schema + " " + c.OldName + " -> " + c.Name
+	default:
+		return "modified " + c.Schema + " " + c.Name
+	}
+}
@@ -0,0 +1,64 @@
+package rpsl_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestDiffTree(t *testing.T) {
+	is := is.New(t)
+
+	base := rpsl.ParseAll(strings.NewReader(cleanDoc(txtAllObjects)))
+	old := rpsl.NewRPSL(rpsl.WithRPSLDir(writeRegistry(t, base)))
+
+	lis := rpsl.ParseAll(strings.NewReader(cleanDoc(txtMetaSchemas + txtSchemas + txtDN42Objects + txtRoleObject + txtMnterObject + `
+        person:             Xuu
+        nic-hdl:            XUU-DN42
+        mnt-by:             XUU-MNT
+        source:             DN42
+
+        inetnum:            172.21.64.8 - 172.21.64.15
+        cidr:               172.21.64.8/29
+        netname:            XUU-TEST-NET
+        descr:              Xuu TestNet
+        country:            US
+        admin-c:            SOURIS-DN42
+        tech-c:             SOURIS-DN42
+        mnt-by:             XUU-MNT
+        nserver:            lavana.sjc.xuu.dn42
+        nserver:            kapha.mtr.xuu.dn42
+        nserver:            rishi.bre.xuu.dn42
+        status:             ALLOCATED
+        remarks:            This is a transfernet.
+        source:             DN42
+
+        mntner:             FOO-MNT
+        mnt-by:             FOO-MNT
+        source:             DN42
+        `)))
+	new := rpsl.NewRPSL(rpsl.WithRPSLDir(writeRegistry(t, lis)))
+
+	d, err := rpsl.DiffTree(old, new)
+	is.NoErr(err)
+	is.Equal(d.String(), cleanDoc(`
+        renamed inetnum 172.21.64.0/29 -> 172.21.64.8/29
+        created mntner FOO-MNT
+        modified person XUU-DN42
+        `)+"\n")
+
+	person := d.BySchema()["person"][0]
+	is.Equal(person.Old.Get("contact").Text(), "xmpp:xuu@xmpp.dn42")
+	is.Equal(person.Diff.Changes[0].Op, rpsl.DiffRemove)
+
+	changes := d.Changes()
+	is.Equal(len(changes), 4)
+	is.Equal(changes[0].Type, rpsl.ChangeDelete)
+	is.Equal(changes[1].Object.Name(), "172.21.64.8/29")
+
+	d, err = rpsl.DiffTree(old, old)
+	is.NoErr(err)
+	is.Equal(len(d.Objects), 0)
+}
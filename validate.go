@@ -0,0 +1,103 @@
+package rpsl
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ValidationError lists the problems found when validating an object.
+type ValidationError struct {
+	Schema   string
+	Name     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s %s: %s", e.Schema, e.Name, strings.Join(e.Problems, "; "))
+}
+
+// Validate object against the schema rules. Checks required and single keys,
+// unknown keys, multiline values on oneline keys and values that fail to parse
+// with the key spec.
+func (s *Schema) Validate(dom *Object) error {
+	e := &ValidationError{Schema: s.Name, Name: dom.Name()}
+
+	keys := make([]string, 0, len(s.Rules))
+	for key := range s.Rules {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		rules := s.Rules[key]
+		n := len(dom.keys[key])
+		if n == 0 && rules.Has("required") {
+			e.Problems = append(e.Problems, fmt.Sprintf("missing required key %s", key))
+		}
+		if n > 1 && rules.Has("single") {
+			e.Problems = append(e.Problems, fmt.Sprintf("key %s allowed only once", key))
+		}
+	}
+
+	for _, attr := range dom.Attrs() {
+		rules, ok := s.Rules[attr.Name]
+		if !ok {
+			e.Problems = append(e.Problems, fmt.Sprintf("unknown key %s", attr.Name))
+			continue
+		}
+		if rules.Has("oneline") && len(attr.rows) > 1 {
+			e.Problems = append(e.Problems, fmt.Sprintf("key %s must be a single line", attr.Name))
+		}
+
+		args := attr.Args()
+		for _, name := range args.Keys() {
+			if err, ok := args.Get(name).(*ErrArg); ok {
+				e.Problems = append(e.Problems, fmt.Sprintf("key %s argument %s: %v", attr.Name, name, err))
+			}
+		}
+	}
+
+	if len(e.Problems) > 0 {
+		return e
+	}
+
+	return nil
+}
+
+// Lookups returns the names of schemas a lookup choice refers to. A choice is
+// either a schema name or the primary key shared by one or more schemas.
+func (s *Schemas) Lookups(choice string) []string {
+	if _, ok := s.m[choice]; ok {
+		return []string{choice}
+	}
+
+	var lis []string
+	for _, schema := range s.Items() {
+		if schema.Primary == choice {
+			lis = append(lis, schema.Name)
+		}
+	}
+	sort.Strings(lis)
+
+	return lis
+}
+
+// References returns the lookup arguments of an object that has a schema applied.
+func (dom *Object) References() []*LookupArg {
+	var lis []*LookupArg
+	for _, attr := range dom.Attrs() {
+		if attr.spec == nil {
+			continue
+		}
+
+		args := attr.Args()
+		for _, name := range args.Keys() {
+			if arg, ok := args.Get(name).(*LookupArg); ok {
+				lis = append(lis, arg)
+			}
+		}
+	}
+
+	return lis
+}
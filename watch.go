@@ -0,0 +1,324 @@
+package rpsl
+
+import (
+	"context"
+	"errors"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Event is a change to an object found by a Watcher. Old is nil for creates
+// and New is nil for deletes.
+type Event struct {
+	Type   ChangeType
+	Schema string
+	Name   string
+	Old    *Object
+	New    *Object
+}
+
+// Watcher keeps a Memory registry up to date with the data directory it was
+// loaded from. Changed files are parsed again and applied to the registry,
+// and an event is sent for every object created, modified or deleted.
+//
+// On Linux changes are found with inotify. Elsewhere, or when inotify can not
+// be used, the directory is polled.
+type Watcher struct {
+	// Interval between scans of the directory when polling. Defaults to two
+	// seconds.
+	Interval time.Duration
+
+	// Delay after a change is noticed before files are read, so that changes
+	// made together are applied together. Defaults to 100 milliseconds.
+	Delay time.Duration
+
+	// Poll the directory even when inotify is available.
+	Poll bool
+
+	// ErrorLog receives changes that could not be applied to the registry,
+	// such as a schema that fails to parse. If nil, the log package's
+	// standard logger is used.
+	ErrorLog *log.Logger
+
+	dir    string
+	mem    *Memory
+	events chan *Event
+	files  map[string]fileStat
+}
+
+type fileStat struct {
+	mod  time.Time
+	size int64
+}
+
+// notifier reports the paths of changed files relative to a directory. A
+// schema directory that was created, deleted or moved is reported by name.
+type notifier interface {
+	run(ctx context.Context, changed chan<- string) error
+}
+
+// NewWatcher for the registry data directory dir that updates mem, which
+// should have been loaded from dir with LoadDir.
+func NewWatcher(dir string, mem *Memory) *Watcher {
+	return &Watcher{
+		Interval: 2 * time.Second,
+		Delay:    100 * time.Millisecond,
+		dir:      dir,
+		mem:      mem,
+		events:   make(chan *Event, 64),
+	}
+}
+
+// Events receives a change for each object. It is closed when Run returns.
+// Run waits for events to be received, so the channel must be drained.
+func (w *Watcher) Events() <-chan *Event {
+	return w.events
+}
+
+// Run watches the directory until ctx is done or it can no longer be read.
+// Changes that can't be applied to the registry are logged and skipped.
+func (w *Watcher) Run(ctx context.Context) error {
+	defer close(w.events)
+
+	if !w.Poll {
+		if n, err := newNotifier(w.dir); err == nil {
+			return w.notify(ctx, n)
+		}
+	}
+
+	return w.poll(ctx)
+}
+
+func (w *Watcher) notify(ctx context.Context, n notifier) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	changed := make(chan string)
+	errc := make(chan error, 1)
+	go func() { errc <- n.run(ctx, changed) }()
+
+	pending := make(map[string]bool)
+	var timer <-chan time.Time
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case err := <-errc:
+			return err
+
+		case p := <-changed:
+			pending[p] = true
+			if timer == nil {
+				timer = time.After(w.Delay)
+			}
+
+		case <-timer:
+			timer = nil
+			paths := make([]string, 0, len(pending))
+			for p := range pending {
+				paths = append(paths, p)
+			}
+			pending = make(map[string]bool)
+
+			if err := w.sync(ctx, paths); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (w *Watcher) poll(ctx context.Context) error {
+	if _, err := w.scan(); err != nil {
+		return err
+	}
+
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+
+		case <-ticker.C:
+			paths, err := w.scan()
+			if err != nil {
+				return err
+			}
+			if err := w.sync(ctx, paths); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// scan stats every object file and returns the paths that were created,
+// changed or removed since the last scan.
+func (w *Watcher) scan() ([]string, error) {
+	fsys := os.DirFS(w.dir)
+	files, err := walkRegistry(fsys)
+	if err != nil {
+		return nil, err
+	}
+
+	next := make(map[string]fileStat, len(files))
+	for _, p := range files {
+		fi, err := fs.Stat(fsys, p)
+		if err != nil {
+			continue
+		}
+		next[p] = fileStat{mod: fi.ModTime(), size: fi.Size()}
+	}
+
+	var paths []string
+	for p, st := range next {
+		if old, ok := w.files[p]; !ok || old != st {
+			paths = append(paths, p)
+		}
+	}
+	for p := range w.files {
+		if _, ok := next[p]; !ok {
+			paths = append(paths, p)
+		}
+	}
+	w.files = next
+
+	return paths, nil
+}
+
+// sync parses the files at paths and applies the differences to the registry.
+func (w *Watcher) sync(ctx context.Context, paths []string) error {
+	if len(paths) == 0 {
+		return nil
+	}
+
+	snap := w.mem.Snapshot()
+	paths = w.expand(snap, paths)
+
+	var (
+		changes []*Change
+		events  []*Event
+	)
+	for _, p := range paths {
+		schema, file := path.Split(p)
+		schema = strings.TrimSuffix(schema, "/")
+
+		old, err := snap.LoadObject(schema, file)
+		if err != nil && err != NotFound {
+			return err
+		}
+
+		b, err := os.ReadFile(filepath.Join(w.dir, filepath.FromSlash(p)))
+		if errors.Is(err, fs.ErrNotExist) {
+			if old != nil {
+				changes = append(changes, &Change{Type: ChangeDelete, Object: old})
+				events = append(events, &Event{Type: ChangeDelete, Schema: schema, Name: old.Name(), Old: old})
+			}
+			continue
+		}
+		if err != nil {
+			// The file is read again on its next change.
+			continue
+		}
+
+		dom := ParseObject(string(b))
+		if dom.Schema() == "" || dom.Name() == "" {
+			// Files are often empty for a moment while being written, so
+			// this is not a delete. The file is read again on its next
+			// change.
+			w.logf("rpsl: watch %s: skipped %s: no object", w.dir, p)
+			continue
+		}
+		snap.Schemas().Apply(dom)
+		if old != nil && old.String() == dom.String() {
+			continue
+		}
+
+		// The primary key was changed so the object under the old key is
+		// deleted.
+		if old != nil && (dom.Schema() != schema || FileName(dom.Name()) != file) {
+			changes = append(changes, &Change{Type: ChangeDelete, Object: old})
+			events = append(events, &Event{Type: ChangeDelete, Schema: schema, Name: old.Name(), Old: old})
+
+			old, err = snap.LoadObject(dom.Schema(), dom.Name())
+			if err == NotFound {
+				old = nil
+			} else if err != nil {
+				return err
+			}
+		}
+
+		c := &Change{Type: ChangeModify, Object: dom}
+		if old == nil {
+			c.Type = ChangeCreate
+		}
+		changes = append(changes, c)
+		events = append(events, &Event{Type: c.Type, Schema: dom.Schema(), Name: dom.Name(), Old: old, New: dom.Clone()})
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	if err := w.mem.Apply(changes); err != nil {
+		// The files are read again on their next change.
+		w.logf("rpsl: watch %s: skipped %s: %v", w.dir, strings.Join(paths, ", "), err)
+		return nil
+	}
+
+	for _, e := range events {
+		select {
+		case w.events <- e:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+
+	return nil
+}
+
+// expand replaces schema directories in paths with the files within them and
+// the objects held for the schema, so that a directory that was removed
+// deletes its objects. The result is sorted without duplicates.
+func (w *Watcher) expand(snap *Snapshot, paths []string) []string {
+	seen := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		if strings.Contains(p, "/") {
+			seen[p] = true
+			continue
+		}
+
+		entries, _ := os.ReadDir(filepath.Join(w.dir, p))
+		for _, entry := range entries {
+			if entry.Type().IsRegular() && !strings.HasPrefix(entry.Name(), ".") {
+				seen[path.Join(p, entry.Name())] = true
+			}
+		}
+		for file := range snap.objects[p] {
+			seen[path.Join(p, file)] = true
+		}
+	}
+
+	lis := make([]string, 0, len(seen))
+	for p := range seen {
+		lis = append(lis, p)
+	}
+	sort.Strings(lis)
+
+	return lis
+}
+
+func (w *Watcher) logf(format string, args ...interface{}) {
+	if w.ErrorLog != nil {
+		w.ErrorLog.Printf(format, args...)
+		return
+	}
+	log.Printf(format, args...)
+}
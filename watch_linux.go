@@ -0,0 +1,142 @@
+//go:build linux
+// +build linux
+
+package rpsl
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+const inotifyMask = syscall.IN_CLOSE_WRITE | syscall.IN_CREATE | syscall.IN_DELETE |
+	syscall.IN_MOVED_FROM | syscall.IN_MOVED_TO
+
+// inotify watches the data directory and each schema directory within.
+type inotify struct {
+	fd      int
+	f       *os.File
+	dir     string
+	watches map[int32]string
+}
+
+func newNotifier(dir string) (notifier, error) {
+	fd, err := syscall.InotifyInit1(syscall.IN_CLOEXEC | syscall.IN_NONBLOCK)
+	if err != nil {
+		return nil, os.NewSyscallError("inotify_init1", err)
+	}
+
+	// The file is non-blocking so that closing it interrupts a read. Its Fd
+	// method would make it blocking so fd is kept for adding watches.
+	n := &inotify{fd: fd, f: os.NewFile(uintptr(fd), "inotify"), dir: dir, watches: make(map[int32]string)}
+	if err := n.add(""); err != nil {
+		n.f.Close()
+		return nil, err
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		n.f.Close()
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			if err := n.add(entry.Name()); err != nil {
+				n.f.Close()
+				return nil, err
+			}
+		}
+	}
+
+	return n, nil
+}
+
+// add a watch for the schema directory, or the data directory when empty.
+func (n *inotify) add(schema string) error {
+	wd, err := syscall.InotifyAddWatch(n.fd, filepath.Join(n.dir, schema), inotifyMask)
+	if err != nil {
+		return os.NewSyscallError("inotify_add_watch", err)
+	}
+	n.watches[int32(wd)] = schema
+	return nil
+}
+
+// remove the watch for a schema directory that was deleted or moved away.
+func (n *inotify) remove(schema string) {
+	for wd, s := range n.watches {
+		if s == schema {
+			// The kernel has already removed the watch of a deleted
+			// directory, so errors are expected.
+			syscall.InotifyRmWatch(n.fd, uint32(wd))
+			delete(n.watches, wd)
+		}
+	}
+}
+
+func (n *inotify) run(ctx context.Context, changed chan<- string) error {
+	go func() {
+		<-ctx.Done()
+		n.f.Close()
+	}()
+
+	buf := make([]byte, 64*(syscall.SizeofInotifyEvent+syscall.NAME_MAX+1))
+	for {
+		k, err := n.f.Read(buf)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+			return err
+		}
+
+		var paths []string
+		for off := 0; off+syscall.SizeofInotifyEvent <= k; {
+			ev := (*syscall.InotifyEvent)(unsafe.Pointer(&buf[off]))
+			start := off + syscall.SizeofInotifyEvent
+			off = start + int(ev.Len)
+
+			name := string(bytes.TrimRight(buf[start:off], "\x00"))
+			if name == "" || strings.HasPrefix(name, ".") {
+				continue
+			}
+
+			schema, ok := n.watches[ev.Wd]
+			switch {
+			case !ok:
+				continue
+
+			case schema == "":
+				// Schema directories are watched while they exist. The
+				// watcher reads the files of a directory reported by name.
+				if ev.Mask&syscall.IN_ISDIR == 0 {
+					continue
+				}
+				switch {
+				case ev.Mask&(syscall.IN_CREATE|syscall.IN_MOVED_TO) != 0:
+					if err := n.add(name); err != nil {
+						return err
+					}
+				case ev.Mask&(syscall.IN_DELETE|syscall.IN_MOVED_FROM) != 0:
+					n.remove(name)
+				}
+				paths = append(paths, name)
+
+			case ev.Mask&syscall.IN_ISDIR == 0:
+				paths = append(paths, path.Join(schema, name))
+			}
+		}
+
+		for _, p := range paths {
+			select {
+			case changed <- p:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+}
@@ -0,0 +1,10 @@
+//go:build !linux
+// +build !linux
+
+package rpsl
+
+import "errors"
+
+func newNotifier(dir string) (notifier, error) {
+	return nil, errors.New("file notifications not supported")
+}
@@ -0,0 +1,182 @@
+package rpsl_test
+
+import (
+	"context"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/matryer/is"
+	"rpsl.dn42.us/go-rpsl"
+)
+
+func TestWatcher(t *testing.T) {
+	for _, poll := range []bool{false, true} {
+		poll := poll
+		name := "notify"
+		if poll {
+			name = "poll"
+		}
+
+		t.Run(name, func(t *testing.T) {
+			is := is.New(t)
+
+			dir := writeRegistry(t, rpsl.ParseAll(strings.NewReader(cleanDoc(txtAllObjects))))
+			m, err := rpsl.LoadDir(context.Background(), dir, 0)
+			is.NoErr(err)
+
+			w := rpsl.NewWatcher(dir, m)
+			w.Poll = poll
+			w.Interval = 20 * time.Millisecond
+			w.Delay = 20 * time.Millisecond
+			w.ErrorLog = log.New(ioutil.Discard, "", 0)
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error)
+			go func() { done <- w.Run(ctx) }()
+			defer func() {
+				cancel()
+				is.NoErr(<-done)
+			}()
+
+			next := func() *rpsl.Event {
+				select {
+				case e := <-w.Events():
+					return e
+				case <-time.After(5 * time.Second):
+					t.Fatal("timed out waiting for event")
+					return nil
+				}
+			}
+			write := func(path, txt string) {
+				// Let a poll record the previous state so the change is seen.
+				time.Sleep(50 * time.Millisecond)
+				path = filepath.Join(dir, filepath.FromSlash(path))
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(cleanDoc(txt)), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			write("mntner/XUU-MNT", `
+				mntner: XUU-MNT
+				mnt-by: XUU-MNT
+				remarks: changed
+				source: DN42
+			`)
+			e := next()
+			is.Equal(e.Type, rpsl.ChangeModify)
+			is.Equal(e.Schema, "mntner")
+			is.Equal(e.Name, "XUU-MNT")
+			is.Equal(e.Old.Get("remarks"), nil)
+			is.Equal(e.New.Get("remarks").Text(), "changed")
+
+			mnt, err := m.LoadObject("mntner", "XUU-MNT")
+			is.NoErr(err)
+			is.Equal(mnt.Get("remarks").Text(), "changed")
+
+			write("aut-num/AS4242420000", `
+				aut-num: AS4242420000
+				as-name: XUU-AS
+				mnt-by:  XUU-MNT
+				source:  DN42
+			`)
+			e = next()
+			is.Equal(e.Type, rpsl.ChangeCreate)
+			is.Equal(e.Name, "AS4242420000")
+			is.Equal(e.Old, nil)
+			is.Equal(len(m.Referrers("mntner", "XUU-MNT")), 5)
+
+			time.Sleep(50 * time.Millisecond)
+			is.NoErr(os.Remove(filepath.Join(dir, "role", "SOURIS-DN42")))
+			e = next()
+			is.Equal(e.Type, rpsl.ChangeDelete)
+			is.Equal(e.Name, "SOURIS-DN42")
+			is.Equal(e.New, nil)
+
+			_, err = m.LoadObject("role", "SOURIS-DN42")
+			is.Equal(err, rpsl.NotFound)
+
+			// Changing the primary key deletes the object under the old key.
+			write("aut-num/AS4242420000", `
+				aut-num: AS4242420001
+				as-name: XUU-AS
+				mnt-by:  XUU-MNT
+				source:  DN42
+			`)
+			e = next()
+			is.Equal(e.Type, rpsl.ChangeDelete)
+			is.Equal(e.Name, "AS4242420000")
+			e = next()
+			is.Equal(e.Type, rpsl.ChangeCreate)
+			is.Equal(e.Name, "AS4242420001")
+
+			_, err = m.LoadObject("aut-num", "AS4242420000")
+			is.Equal(err, rpsl.NotFound)
+			_, err = m.LoadObject("aut-num", "AS4242420001")
+			is.NoErr(err)
+
+			// Changes that can't be applied are skipped.
+			write("schema/BROKEN-SCHEMA", `
+				schema: BROKEN-SCHEMA
+				key:    broken required single primary > [lookup
+			`)
+			write("mntner/XUU-MNT", `
+				mntner: XUU-MNT
+				mnt-by: XUU-MNT
+				remarks: changed again
+				source: DN42
+			`)
+			e = next()
+			is.Equal(e.Type, rpsl.ChangeModify)
+			is.Equal(e.New.Get("remarks").Text(), "changed again")
+
+			_, err = m.LoadObject("schema", "BROKEN-SCHEMA")
+			is.Equal(err, rpsl.NotFound)
+
+			// Empty files are skipped rather than deleting the object.
+			write("mntner/XUU-MNT", "")
+			write("mntner/XUU-MNT", "\n% comment only\n")
+			write("aut-num/AS4242420001", `
+				aut-num: AS4242420001
+				as-name: XUU-AS
+				remarks: after truncate
+				mnt-by:  XUU-MNT
+				source:  DN42
+			`)
+			e = next()
+			is.Equal(e.Type, rpsl.ChangeModify)
+			is.Equal(e.Name, "AS4242420001")
+
+			_, err = m.LoadObject("mntner", "XUU-MNT")
+			is.NoErr(err)
+			_, err = m.LoadObject("", "")
+			is.Equal(err, rpsl.NotFound)
+
+			// Moving a schema directory away deletes its objects.
+			persons, err := m.ListObjects("person")
+			is.NoErr(err)
+			is.True(len(persons) > 0)
+
+			time.Sleep(50 * time.Millisecond)
+			moved := dir + "-person"
+			is.NoErr(os.Rename(filepath.Join(dir, "person"), moved))
+			defer os.RemoveAll(moved)
+			for range persons {
+				e = next()
+				is.Equal(e.Type, rpsl.ChangeDelete)
+				is.Equal(e.Schema, "person")
+			}
+
+			persons, err = m.ListObjects("person")
+			is.NoErr(err)
+			is.Equal(len(persons), 0)
+		})
+	}
+}
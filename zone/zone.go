@@ -0,0 +1,270 @@
+// Package zone generates BIND zone file delegations from dns, inetnum and
+// inet6num objects.
+package zone
+
+import (
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"sort"
+	"strings"
+
+	rpsl "rpsl.dn42.us/go-rpsl"
+)
+
+// Record is a single resource record. Names are fully qualified without the
+// trailing dot.
+type Record struct {
+	Name string
+	Type string
+	Data string
+}
+
+func (r Record) String() string {
+	return r.Name + ".\tIN\t" + r.Type + "\t" + r.Data
+}
+
+// Zone is a list of records below an origin.
+type Zone struct {
+	Origin  string
+	Records []Record
+}
+
+// Generate a zone for origin from the delegations in lis. Objects that
+// delegate names outside of origin are skipped. Glue records are added for
+// name servers inside origin. Objects that fail to parse are left out of the
+// zone and their errors returned together with it.
+func Generate(origin string, lis rpsl.ListObject) (*Zone, error) {
+	z := &Zone{Origin: strings.ToLower(strings.TrimSuffix(origin, "."))}
+	seen := make(map[Record]bool)
+
+	var errs errorList
+	for _, dom := range lis {
+		records, err := z.delegations(dom)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s %s: %w", dom.Schema(), dom.Name(), err))
+			continue
+		}
+
+		for _, r := range records {
+			r.Name = strings.ToLower(r.Name)
+			if z.inZone(r.Name) && !seen[r] {
+				seen[r] = true
+				z.Records = append(z.Records, r)
+			}
+		}
+	}
+
+	sort.SliceStable(z.Records, func(i, j int) bool {
+		a, b := z.Records[i], z.Records[j]
+		if c := compareNames(a.Name, b.Name); c != 0 {
+			return c < 0
+		}
+		if typeOrder[a.Type] != typeOrder[b.Type] {
+			return typeOrder[a.Type] < typeOrder[b.Type]
+		}
+		return a.Data < b.Data
+	})
+
+	if len(errs) > 0 {
+		return z, errs
+	}
+	return z, nil
+}
+
+// delegations returns the records delegating the names of dom within the
+// zone. Records may be outside of the zone.
+func (z *Zone) delegations(dom *rpsl.Object) ([]Record, error) {
+	var names []string
+	var cnames []Record
+
+	switch dom.Schema() {
+	case "dns":
+		names = []string{strings.TrimSuffix(dom.Name(), ".")}
+	case "inetnum", "inet6num":
+		if dom.Get("nserver") == nil {
+			return nil, nil
+		}
+		var err error
+		names, cnames, err = ReverseNames(dom.Get("cidr").Text())
+		if err != nil {
+			return nil, err
+		}
+	default:
+		return nil, nil
+	}
+
+	var records []Record
+	for _, name := range names {
+		if !z.inZone(name) || strings.EqualFold(name, z.Origin) {
+			continue
+		}
+
+		for _, ns := range dom.GetAll("nserver") {
+			fields := ns.Fields()
+			if len(fields) == 0 {
+				continue
+			}
+			host := strings.TrimSuffix(fields[0], ".")
+			records = append(records, Record{name, "NS", strings.ToLower(host) + "."})
+
+			for _, addr := range fields[1:] {
+				ip := net.ParseIP(addr)
+				if ip == nil {
+					return nil, fmt.Errorf("invalid nserver address %q", addr)
+				}
+				if ip.To4() != nil {
+					records = append(records, Record{host, "A", ip.String()})
+				} else {
+					records = append(records, Record{host, "AAAA", ip.String()})
+				}
+			}
+		}
+
+		for _, ds := range dom.GetAll("ds-rdata") {
+			if text := strings.Join(ds.Fields(), " "); text != "" {
+				records = append(records, Record{name, "DS", text})
+			}
+		}
+	}
+
+	return append(records, cnames...), nil
+}
+
+type errorList []error
+
+func (lis errorList) Error() string {
+	s := make([]string, len(lis))
+	for i, err := range lis {
+		s[i] = err.Error()
+	}
+	return strings.Join(s, "\n")
+}
+
+var typeOrder = map[string]int{"NS": 0, "DS": 1, "A": 2, "AAAA": 3, "CNAME": 4}
+
+func (z *Zone) inZone(name string) bool {
+	name = strings.ToLower(name)
+	return name == z.Origin || strings.HasSuffix(name, "."+z.Origin)
+}
+
+// compareNames orders names by their labels from the root down.
+func compareNames(a, b string) int {
+	al, bl := strings.Split(a, "."), strings.Split(b, ".")
+	for i, j := len(al)-1, len(bl)-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if c := strings.Compare(al[i], bl[j]); c != 0 {
+			return c
+		}
+	}
+	return len(al) - len(bl)
+}
+
+// WriteBind writes the zone records in BIND zone file format.
+func (z *Zone) WriteBind(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("$ORIGIN " + z.Origin + ".\n")
+	for _, r := range z.Records {
+		b.WriteString(r.String())
+		b.WriteRune('\n')
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// ReverseNames returns the reverse zone names that delegate cidr. Prefixes
+// not on an octet or nibble boundary are split into the zones they cover.
+// IPv4 prefixes longer than /24 use RFC 2317 classless delegation, which also
+// returns the CNAME records for the parent zone.
+func ReverseNames(cidr string) ([]string, []Record, error) {
+	_, prefix, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, nil, err
+	}
+	ones, _ := prefix.Mask.Size()
+
+	if ip := prefix.IP.To4(); ip != nil {
+		if ones > 24 {
+			return classless(ip, ones)
+		}
+
+		var names []string
+		n := (ones + 7) / 8
+		for _, sub := range subnets(prefix, n*8) {
+			names = append(names, reverseV4(sub.IP.To4()[:n]))
+		}
+		return names, nil, nil
+	}
+
+	var names []string
+	n := (ones + 3) / 4
+	for _, sub := range subnets(prefix, n*4) {
+		names = append(names, reverseV6(sub.IP, n))
+	}
+
+	return names, nil, nil
+}
+
+func classless(ip net.IP, ones int) ([]string, []Record, error) {
+	parent := reverseV4(ip[:3])
+	name := fmt.Sprintf("%d/%d.%s", ip[3], ones, parent)
+
+	size := 1 << uint(32-ones)
+	cnames := make([]Record, size)
+	for i := 0; i < size; i++ {
+		host := int(ip[3]) + i
+		cnames[i] = Record{
+			Name: fmt.Sprintf("%d.%s", host, parent),
+			Type: "CNAME",
+			Data: fmt.Sprintf("%d.%s.", host, name),
+		}
+	}
+
+	return []string{name}, cnames, nil
+}
+
+func reverseV4(octets []byte) string {
+	labels := make([]string, 0, len(octets)+2)
+	for i := len(octets) - 1; i >= 0; i-- {
+		labels = append(labels, fmt.Sprint(octets[i]))
+	}
+	return strings.Join(append(labels, "in-addr", "arpa"), ".")
+}
+
+func reverseV6(ip net.IP, nibbles int) string {
+	const hex = "0123456789abcdef"
+
+	labels := make([]string, 0, nibbles+2)
+	for i := nibbles - 1; i >= 0; i-- {
+		b := ip[i/2]
+		if i%2 == 0 {
+			b >>= 4
+		}
+		labels = append(labels, string(hex[b&0xf]))
+	}
+	return strings.Join(append(labels, "ip6", "arpa"), ".")
+}
+
+// subnets splits prefix into the networks of length n it contains.
+func subnets(prefix *net.IPNet, n int) []*net.IPNet {
+	ones, bits := prefix.Mask.Size()
+	if n <= ones {
+		return []*net.IPNet{prefix}
+	}
+
+	count := 1 << uint(n-ones)
+	lis := make([]*net.IPNet, count)
+	base := new(big.Int).SetBytes(prefix.IP)
+	for i := 0; i < count; i++ {
+		v := new(big.Int).Lsh(big.NewInt(int64(i)), uint(bits-n))
+		v.Add(v, base)
+
+		ip := make(net.IP, len(prefix.IP))
+		b := v.Bytes()
+		copy(ip[len(ip)-len(b):], b)
+		lis[i] = &net.IPNet{IP: ip, Mask: net.CIDRMask(n, bits)}
+	}
+
+	return lis
+}
@@ -0,0 +1,110 @@
+package zone_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/matryer/is"
+	rpsl "rpsl.dn42.us/go-rpsl"
+	"rpsl.dn42.us/go-rpsl/zone"
+)
+
+const txtObjects = `dns:                xuu.dn42
+nserver:            ns1.xuu.dn42 172.21.64.1
+nserver:            ns1.xuu.dn42 fd42:4242:2601::1
+nserver:            ns.example.com
+ds-rdata:           12345 13 2 ABCDEF0123
+mnt-by:             XUU-MNT
+
+dns:                other.example
+nserver:            ns.other.example
+
+inetnum:            172.21.64.0 - 172.21.64.7
+cidr:               172.21.64.0/29
+nserver:            ns1.xuu.dn42
+
+inetnum:            172.22.0.0 - 172.22.1.255
+cidr:               172.22.0.0/23
+nserver:            ns1.xuu.dn42
+ds-rdata:           1 13 2 AA
+
+inetnum:            172.23.0.0 - 172.23.0.255
+cidr:               172.23.0.0/24
+
+inet6num:           fd42:4242:2601:0000:0000:0000:0000:0000 - fd42:4242:2601:ffff:ffff:ffff:ffff:ffff
+cidr:               fd42:4242:2601::/47
+nserver:            ns1.xuu.dn42
+`
+
+func TestForward(t *testing.T) {
+	is := is.New(t)
+
+	z, err := zone.Generate("dn42.", rpsl.ParseAll(strings.NewReader(txtObjects)))
+	is.NoErr(err)
+
+	var b strings.Builder
+	is.NoErr(z.WriteBind(&b))
+	is.Equal(b.String(), `$ORIGIN dn42.
+xuu.dn42.	IN	NS	ns.example.com.
+xuu.dn42.	IN	NS	ns1.xuu.dn42.
+xuu.dn42.	IN	DS	12345 13 2 ABCDEF0123
+ns1.xuu.dn42.	IN	A	172.21.64.1
+ns1.xuu.dn42.	IN	AAAA	fd42:4242:2601::1
+`)
+}
+
+func TestReverse(t *testing.T) {
+	is := is.New(t)
+
+	lis := rpsl.ParseAll(strings.NewReader(txtObjects))
+
+	z, err := zone.Generate("172.in-addr.arpa", lis)
+	is.NoErr(err)
+
+	var b strings.Builder
+	is.NoErr(z.WriteBind(&b))
+	is.Equal(b.String(), `$ORIGIN 172.in-addr.arpa.
+0.64.21.172.in-addr.arpa.	IN	CNAME	0.0/29.64.21.172.in-addr.arpa.
+0/29.64.21.172.in-addr.arpa.	IN	NS	ns1.xuu.dn42.
+1.64.21.172.in-addr.arpa.	IN	CNAME	1.0/29.64.21.172.in-addr.arpa.
+2.64.21.172.in-addr.arpa.	IN	CNAME	2.0/29.64.21.172.in-addr.arpa.
+3.64.21.172.in-addr.arpa.	IN	CNAME	3.0/29.64.21.172.in-addr.arpa.
+4.64.21.172.in-addr.arpa.	IN	CNAME	4.0/29.64.21.172.in-addr.arpa.
+5.64.21.172.in-addr.arpa.	IN	CNAME	5.0/29.64.21.172.in-addr.arpa.
+6.64.21.172.in-addr.arpa.	IN	CNAME	6.0/29.64.21.172.in-addr.arpa.
+7.64.21.172.in-addr.arpa.	IN	CNAME	7.0/29.64.21.172.in-addr.arpa.
+0.22.172.in-addr.arpa.	IN	NS	ns1.xuu.dn42.
+0.22.172.in-addr.arpa.	IN	DS	1 13 2 AA
+1.22.172.in-addr.arpa.	IN	NS	ns1.xuu.dn42.
+1.22.172.in-addr.arpa.	IN	DS	1 13 2 AA
+`)
+
+	z, err = zone.Generate("ip6.arpa", lis)
+	is.NoErr(err)
+	is.Equal(len(z.Records), 2)
+	is.Equal(z.Records[0].Name, "0.0.6.2.2.4.2.4.2.4.d.f.ip6.arpa")
+	is.Equal(z.Records[1].Name, "1.0.6.2.2.4.2.4.2.4.d.f.ip6.arpa")
+
+	// Bad objects are skipped and reported without losing the others.
+	bad := rpsl.ParseAll(strings.NewReader("inetnum: 172.23.0.0\ncidr: 172.23.0.0\nnserver: ns1.bad.dn42\n\ninetnum: 172.23.1.0/24\ncidr: 172.23.1.0/24\nnserver: ns1.bad.dn42 not-an-ip\n"))
+	z, err = zone.Generate("172.in-addr.arpa", append(bad, lis...))
+	is.True(err != nil)
+	is.Equal(strings.Count(err.Error(), "\n"), 1)
+	is.Equal(len(z.Records), 13)
+}
+
+func TestReverseNames(t *testing.T) {
+	is := is.New(t)
+
+	names, cnames, err := zone.ReverseNames("10.0.0.0/8")
+	is.NoErr(err)
+	is.Equal(names, []string{"10.in-addr.arpa"})
+	is.Equal(len(cnames), 0)
+
+	names, _, err = zone.ReverseNames("172.20.0.0/15")
+	is.NoErr(err)
+	is.Equal(names, []string{"20.172.in-addr.arpa", "21.172.in-addr.arpa"})
+
+	_, _, err = zone.ReverseNames("172.20.0.0")
+	is.True(err != nil)
+}